@@ -0,0 +1,260 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Common audit outcomes
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+	AuditDenied  = "denied"
+)
+
+const (
+	// defaultAuditDirMode is the permission used when creating the audit directory
+	defaultAuditDirMode os.FileMode = 0700
+)
+
+var (
+	// ErrAuditDisabled is returned by AuditEvent.Msg when no audit log is configured
+	ErrAuditDisabled = errors.New("audit log not configured")
+
+	// ErrAuditSchema is returned by AuditEvent.Msg when a required field is missing
+	ErrAuditSchema = errors.New("audit event missing required fields")
+)
+
+// AuditConfig configures the audit log written by Logger.Audit. Audit entries
+// go to their own file with its own rotation and permissions. Retention
+// defaults to keeping every rotated file. Config.HashChain and
+// Config.Encryption apply to the audit file as well; Redact and Pseudonym do
+// not, so that entries keep the exact actor and target they were given.
+type AuditConfig struct {
+	Filename   string      // Audit file name; empty disables Audit
+	Dir        string      // Directory of the audit file (default: LogDir)
+	DirMode    os.FileMode // Permissions of Dir when created (default: 0700)
+	FileMode   os.FileMode // Permissions of the audit file and backups (default: 0600)
+	MaxSizeMB  int         // Size at which the audit file rotates (default: 10)
+	MaxBackups int         // Rotated audit files kept (default: 0, all)
+	MaxAgeDays int         // Days rotated audit files are kept (default: 0, forever)
+}
+
+// auditSink writes audit entries, syncing each one to disk
+type auditSink struct {
+	w        *fileHandle // nil when the audit log could not be opened
+	err      error       // Why the audit log is unavailable
+	conflict error       // Set when another logger opened the file with other settings
+}
+
+// openAuditSink opens the audit file described by cfg.Audit. Failures are
+// kept in the sink and returned for every audit entry.
+func openAuditSink(cfg Config, encKeys *encryptionKeys) *auditSink {
+	a := cfg.Audit
+	if a.Dir == "" {
+		a.Dir = cfg.LogDir
+	}
+	if a.DirMode == 0 {
+		a.DirMode = defaultAuditDirMode
+	}
+	if a.FileMode == 0 {
+		a.FileMode = defaultFileMode
+	}
+	if a.MaxSizeMB == 0 {
+		a.MaxSizeMB = 10
+	}
+
+	a.Dir, a.Filename = filepath.Clean(a.Dir), filepath.Clean(a.Filename)
+	if hasParentRef(a.Dir) {
+		return &auditSink{err: errors.New("path traversal detected in audit Dir: " + a.Dir)}
+	}
+	if !validFilename(a.Filename) {
+		return &auditSink{err: errors.New("invalid audit filename (contains path separators or traversal): " + a.Filename)}
+	}
+
+	settings := fileSettings{
+		maxSizeMB:  a.MaxSizeMB,
+		maxBackups: a.MaxBackups,
+		maxAge:     time.Duration(a.MaxAgeDays) * 24 * time.Hour,
+		mode:       a.FileMode,
+		hashChain:  cfg.HashChain.Enabled,
+		keyID:      cfg.Encryption.KeyID,
+	}
+	h, reused, err := acquireFile(filepath.Join(sharedDir(cfg.AllowedRoot, a.Dir), a.Filename), func() (io.WriteCloser, syncer, error) {
+		var root *os.Root
+		var err error
+		if cfg.AllowedRoot != "" {
+			root, err = openLogRoot(cfg.AllowedRoot, a.Dir, a.DirMode)
+		} else if err = os.MkdirAll(a.Dir, a.DirMode); err == nil {
+			root, err = os.OpenRoot(a.Dir)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		opts := rotateOptions{
+			maxSize:    int64(settings.maxSizeMB) * 1024 * 1024,
+			maxBackups: settings.maxBackups,
+			maxAge:     settings.maxAge,
+			mode:       settings.mode,
+			gid:        -1,
+			encrypt:    encKeys,
+			sync:       true,
+		}
+		var w io.WriteCloser
+		if cfg.HashChain.Enabled {
+			w, err = newChainWriter(root, a.Filename, opts, cfg.HashChain)
+		} else {
+			w, err = newRotatingWriter(root, a.Filename, opts)
+		}
+		if err != nil {
+			_ = root.Close()
+			return nil, nil, err
+		}
+		return w, w.(syncer), nil
+	}, settings)
+	if err != nil {
+		return &auditSink{err: err}
+	}
+	s := &auditSink{w: h}
+	if reused {
+		s.conflict = h.conflict(settings)
+	}
+	return s
+}
+
+// write writes and syncs one entry
+func (s *auditSink) write(p []byte) error {
+	if s == nil {
+		return ErrAuditDisabled
+	}
+	if s.err != nil {
+		return fmt.Errorf("audit log unavailable: %w", s.err)
+	}
+	_, err := s.w.Write(p)
+	return err
+}
+
+// Close closes the audit file
+func (s *auditSink) Close() error {
+	if s == nil || s.w == nil {
+		return nil
+	}
+	return s.w.Close()
+}
+
+// AuditEvent is an audit entry being built. Actor, Action, Target and Outcome
+// are required. Unlike Info or Error, Msg writes synchronously, syncs the
+// entry to disk and returns any failure. An AuditEvent must not be reused
+// after Msg.
+type AuditEvent struct {
+	sink                           *auditSink
+	actor, action, target, outcome string
+	fields                         []func(*zerolog.Event)
+}
+
+// Audit starts an audit entry. Audit entries are never sampled, buffered or
+// dropped; they are written to the audit file configured in Config.Audit.
+func (l *Logger) Audit() *AuditEvent {
+	return &AuditEvent{sink: l.audit}
+}
+
+// Actor sets who performed the action
+func (e *AuditEvent) Actor(actor string) *AuditEvent {
+	e.actor = actor
+	return e
+}
+
+// Action sets what was done, such as "user.delete"
+func (e *AuditEvent) Action(action string) *AuditEvent {
+	e.action = action
+	return e
+}
+
+// Target sets what the action was performed on
+func (e *AuditEvent) Target(target string) *AuditEvent {
+	e.target = target
+	return e
+}
+
+// Outcome sets the result, such as AuditSuccess, AuditFailure or AuditDenied
+func (e *AuditEvent) Outcome(outcome string) *AuditEvent {
+	e.outcome = outcome
+	return e
+}
+
+// Str adds a string field
+func (e *AuditEvent) Str(key, value string) *AuditEvent {
+	e.fields = append(e.fields, func(ze *zerolog.Event) { ze.Str(key, value) })
+	return e
+}
+
+// Int adds an integer field
+func (e *AuditEvent) Int(key string, value int) *AuditEvent {
+	e.fields = append(e.fields, func(ze *zerolog.Event) { ze.Int(key, value) })
+	return e
+}
+
+// Bool adds a boolean field
+func (e *AuditEvent) Bool(key string, value bool) *AuditEvent {
+	e.fields = append(e.fields, func(ze *zerolog.Event) { ze.Bool(key, value) })
+	return e
+}
+
+// Time adds a time field
+func (e *AuditEvent) Time(key string, value time.Time) *AuditEvent {
+	e.fields = append(e.fields, func(ze *zerolog.Event) { ze.Time(key, value) })
+	return e
+}
+
+// Err adds an "error" field
+func (e *AuditEvent) Err(err error) *AuditEvent {
+	e.fields = append(e.fields, func(ze *zerolog.Event) { ze.Err(err) })
+	return e
+}
+
+// Interface adds a field of any type. Struct values honor log struct tags
+// (see LogValue).
+func (e *AuditEvent) Interface(key string, value interface{}) *AuditEvent {
+	value = LogValue(value)
+	e.fields = append(e.fields, func(ze *zerolog.Event) { ze.Interface(key, value) })
+	return e
+}
+
+// Msg writes the entry with msg and syncs it to disk. It returns an error
+// wrapping ErrAuditSchema if a required field is empty, ErrAuditDisabled
+// if no audit log is configured, or the write or sync error.
+func (e *AuditEvent) Msg(msg string) error {
+	var missing []string
+	for _, f := range []struct{ name, value string }{
+		{"actor", e.actor}, {"action", e.action}, {"target", e.target}, {"outcome", e.outcome},
+	} {
+		if f.value == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrAuditSchema, strings.Join(missing, ", "))
+	}
+
+	var buf bytes.Buffer
+	zl := zerolog.New(&buf)
+	ze := zl.Log().
+		Timestamp().
+		Str("actor", e.actor).
+		Str("action", e.action).
+		Str("target", e.target).
+		Str("outcome", e.outcome)
+	for _, f := range e.fields {
+		f(ze)
+	}
+	ze.Msg(msg)
+	return e.sink.write(buf.Bytes())
+}
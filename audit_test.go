@@ -0,0 +1,171 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAuditWritesEntry(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "app.log", Audit: AuditConfig{Filename: "audit.log"}})
+	err := logger.WithField("request_id", "r1").Audit().
+		Actor("alice").
+		Action("user.delete").
+		Target("user:42").
+		Outcome(AuditSuccess).
+		Str("ip", "192.0.2.1").
+		Int("affected", 1).
+		Msg("Deleted user")
+	if err != nil {
+		t.Fatalf("Audit returned error: %v", err)
+	}
+	logger.Info().Msg("application entry")
+	logger.Close()
+
+	audit := readLogFile(t, filepath.Join(tmpDir, "audit.log"))
+	want := `"actor":"alice","action":"user.delete","target":"user:42","outcome":"success","ip":"192.0.2.1","affected":1,"message":"Deleted user"}`
+	if !strings.HasPrefix(audit, `{"time":"`) || !strings.HasSuffix(audit, want+"\n") {
+		t.Errorf("Unexpected audit entry: %s", audit)
+	}
+	if strings.Contains(audit, "application entry") {
+		t.Error("Expected application entries to stay out of the audit log")
+	}
+	if strings.Contains(readLogFile(t, filepath.Join(tmpDir, "app.log")), "user.delete") {
+		t.Error("Expected audit entries to stay out of the application log")
+	}
+}
+
+func TestAuditRequiresSchema(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Audit: AuditConfig{Filename: "audit.log"}})
+	err := logger.Audit().Actor("alice").Outcome(AuditDenied).Msg("incomplete")
+	logger.Close()
+
+	if !errors.Is(err, ErrAuditSchema) || !strings.Contains(err.Error(), "action, target") {
+		t.Errorf("Expected schema error naming action and target, got %v", err)
+	}
+	if audit := readLogFile(t, filepath.Join(tmpDir, "audit.log")); audit != "" {
+		t.Errorf("Expected nothing written for an invalid event, got %s", audit)
+	}
+}
+
+func TestAuditReturnsErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	disabled := New(Config{LogDir: tmpDir})
+	defer disabled.Close()
+	if err := disabled.Audit().Actor("a").Action("b").Target("c").Outcome(AuditSuccess).Msg(""); !errors.Is(err, ErrAuditDisabled) {
+		t.Errorf("Expected ErrAuditDisabled, got %v", err)
+	}
+
+	invalid := New(Config{LogDir: tmpDir, Filename: "invalid.log", Audit: AuditConfig{Filename: "../audit.log"}})
+	err := invalid.Audit().Actor("a").Action("b").Target("c").Outcome(AuditSuccess).Msg("")
+	invalid.Close()
+	if err == nil || !strings.Contains(err.Error(), "audit log unavailable") {
+		t.Errorf("Expected unavailable audit log error, got %v", err)
+	}
+	if !strings.Contains(readLogFile(t, filepath.Join(tmpDir, "invalid.log")), "Failed to open audit log") {
+		t.Error("Expected the audit log failure to be logged")
+	}
+
+	// A failing write is reported to the caller instead of being dropped
+	failing := New(Config{LogDir: tmpDir, Audit: AuditConfig{Filename: "failing.log"}})
+	failing.audit.w.w.(*rotatingWriter).file.Close()
+	err = failing.Audit().Actor("a").Action("b").Target("c").Outcome(AuditFailure).Msg("")
+	failing.Close()
+	if err == nil {
+		t.Error("Expected write error to be returned")
+	}
+}
+
+func TestAuditSeparateDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	auditDir := filepath.Join(tmpDir, "audit")
+
+	logger := New(Config{LogDir: filepath.Join(tmpDir, "app"), Audit: AuditConfig{Filename: "audit.log", Dir: auditDir}})
+	if err := logger.Audit().Actor("a").Action("b").Target("c").Outcome(AuditSuccess).Msg("separate"); err != nil {
+		t.Fatalf("Audit returned error: %v", err)
+	}
+	logger.Close()
+
+	if !strings.Contains(readLogFile(t, filepath.Join(auditDir, "audit.log")), `"message":"separate"`) {
+		t.Error("Expected audit entry in the audit directory")
+	}
+}
+
+func TestAuditConcurrent(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Audit: AuditConfig{Filename: "audit.log"}})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := logger.Audit().Actor("worker").Action("job.run").Target("job").Outcome(AuditSuccess).Int("i", i).Msg(""); err != nil {
+				t.Errorf("Audit returned error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	logger.Close()
+
+	lines := strings.Split(strings.TrimSpace(readLogFile(t, filepath.Join(tmpDir, "audit.log"))), "\n")
+	if len(lines) != 20 {
+		t.Fatalf("Expected 20 audit entries, got %d", len(lines))
+	}
+	for _, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Errorf("Expected one JSON entry per line, got %s", line)
+		}
+	}
+}
+
+func TestAuditHashChainAndEncryption(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := New(Config{
+		LogDir:     tmpDir,
+		Filename:   "app.log",
+		Redact:     RedactConfig{Rules: []RedactRule{{Key: "target"}}},
+		HashChain:  HashChainConfig{Enabled: true},
+		Encryption: EncryptionConfig{KeyID: "k1", Keys: map[string][]byte{"k1": testEncKey1}},
+		Audit:      AuditConfig{Filename: "audit.log"},
+	})
+	for _, target := range []string{"alice@example.com", "bob@example.com"} {
+		if err := logger.Audit().Actor("admin").Action("user.delete").Target(target).Outcome(AuditSuccess).Msg(""); err != nil {
+			t.Fatalf("Audit returned error: %v", err)
+		}
+	}
+	logger.Close()
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "audit.log"))
+	if err != nil {
+		t.Fatalf("ReadFile returned error: %v", err)
+	}
+	if strings.Contains(string(data), "alice@example.com") {
+		t.Fatal("Expected the audit file to be encrypted")
+	}
+	plain, err := decrypt(data)
+	if err != nil {
+		t.Fatalf("decrypt returned error: %v", err)
+	}
+	// Redaction does not apply, so the target is kept exactly
+	if !strings.Contains(plain, `"target":"alice@example.com"`) {
+		t.Errorf("Expected the unredacted target: %s", plain)
+	}
+	v := &ChainVerifier{}
+	if err := v.Verify("audit.log", strings.NewReader(plain)); err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if v.LastSeq() != 2 {
+		t.Errorf("Expected 2 chained entries, got %d", v.LastSeq())
+	}
+}
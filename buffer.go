@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// defaultBufferSize is the number of entries a buffer holds by default
+const defaultBufferSize = 100
+
+// BufferConfig configures the fingers-crossed buffer created by WithBuffer
+type BufferConfig struct {
+	Size int           // Entries held before the oldest are dropped (default: 100)
+	TTL  time.Duration // Age after which held entries are dropped (default: 0, held until the scope ends)
+}
+
+// bufferWriter holds debug and info entries until an error-level entry
+// arrives, then writes them ahead of it and passes everything through. Warn
+// entries are written directly.
+type bufferWriter struct {
+	next io.Writer
+	size int
+	ttl  time.Duration
+
+	mu        sync.Mutex
+	entries   []bufferedEntry // Oldest first
+	triggered bool            // An error-level entry was written; stop buffering
+	ended     bool            // The scope ended; drop debug and info entries
+}
+
+// bufferedEntry is one held entry and when it was written
+type bufferedEntry struct {
+	at    time.Time
+	level zerolog.Level
+	line  []byte
+}
+
+// newBufferWriter returns a buffer in front of next
+func newBufferWriter(next io.Writer, cfg BufferConfig) *bufferWriter {
+	w := &bufferWriter{next: next, size: cfg.Size, ttl: cfg.TTL}
+	if w.size <= 0 {
+		w.size = defaultBufferSize
+	}
+	return w
+}
+
+// Write implements io.Writer for entries without a level, which are never held
+func (w *bufferWriter) Write(p []byte) (int, error) {
+	return w.next.Write(p)
+}
+
+// WriteLevel implements zerolog.LevelWriter
+func (w *bufferWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level == zerolog.NoLevel || level == zerolog.WarnLevel {
+		return w.writeNext(level, p)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.triggered {
+		return w.writeNext(level, p)
+	}
+	if level < zerolog.WarnLevel {
+		if w.ended {
+			return len(p), nil
+		}
+		now := time.Now()
+		w.expireLocked(now)
+		if len(w.entries) == w.size {
+			w.entries[0] = bufferedEntry{}
+			w.entries = w.entries[1:]
+		}
+		// zerolog reuses p once Write returns
+		w.entries = append(w.entries, bufferedEntry{at: now, level: level, line: append([]byte(nil), p...)})
+		return len(p), nil
+	}
+
+	w.triggered = true
+	w.expireLocked(time.Now())
+	var errs []error
+	for _, e := range w.entries {
+		if _, err := w.writeNext(e.level, e.line); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	w.entries = nil
+	n, err := w.writeNext(level, p)
+	if err != nil {
+		return n, err
+	}
+	return n, errors.Join(errs...)
+}
+
+// writeNext passes an entry on, keeping its level for an enclosing buffer
+func (w *bufferWriter) writeNext(level zerolog.Level, p []byte) (int, error) {
+	if lw, ok := w.next.(zerolog.LevelWriter); ok {
+		return lw.WriteLevel(level, p)
+	}
+	return w.next.Write(p)
+}
+
+// expireLocked drops entries older than the TTL. It must be called with w.mu
+// held.
+func (w *bufferWriter) expireLocked(now time.Time) {
+	if w.ttl <= 0 {
+		return
+	}
+	i := 0
+	for i < len(w.entries) && now.Sub(w.entries[i].at) > w.ttl {
+		w.entries[i] = bufferedEntry{}
+		i++
+	}
+	w.entries = w.entries[i:]
+}
+
+// end discards the held entries. Later debug and info entries are dropped
+// unless an error-level entry was already written.
+func (w *bufferWriter) end() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.entries = nil
+	w.ended = true
+}
+
+// WithBuffer returns a child logger that holds debug and info entries in
+// memory, for example for one request. When an error-level entry is logged
+// through it or its descendants, the held entries are written ahead of the
+// error and later entries are written directly. Otherwise the held entries
+// are discarded by the returned end function:
+//
+//	reqLog, end := log.WithBuffer(logger.BufferConfig{Size: 200})
+//	defer end()
+//	ctx = reqLog.WithContext(ctx) // zerolog.Ctx(ctx) buffers too
+//
+// Fields, hooks and the context bound with Ctx carry over to the child.
+func (l *Logger) WithBuffer(cfg BufferConfig) (*Logger, func()) {
+	if l.output == nil {
+		return l.derive(l.Logger), func() {}
+	}
+	w := newBufferWriter(l.output, cfg)
+	zl := l.Logger.Output(w)
+	if l.ctx != nil {
+		zl = zl.With().Ctx(l.ctx).Logger()
+	}
+	child := l.derive(zl)
+	child.output = w
+	return child, w.end
+}
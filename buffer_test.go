@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestBufferFlushesOnError(t *testing.T) {
+	logger, path := newTestLogger(t, Config{Level: "debug"})
+	reqLog, end := logger.WithBuffer(BufferConfig{})
+	reqLog = reqLog.WithField("request_id", "r1")
+
+	reqLog.Debug().Msg("parsed request")
+	reqLog.Info().Msg("calling backend")
+	logger.Info().Msg("unrelated")
+	if logStr := readLogFile(t, path); strings.Contains(logStr, "parsed request") {
+		t.Fatalf("Expected debug entries to be held: %s", logStr)
+	}
+
+	reqLog.Error().Msg("backend failed")
+	reqLog.Debug().Msg("after the error")
+	end()
+	logger.Close()
+
+	lines := strings.Split(strings.TrimSpace(readLogFile(t, path)), "\n")
+	want := []string{"unrelated", "parsed request", "calling backend", "backend failed", "after the error"}
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d entries, got %v", len(want), lines)
+	}
+	for i, msg := range want {
+		if !strings.Contains(lines[i], `"message":"`+msg+`"`) {
+			t.Errorf("Line %d: expected %q, got %s", i, msg, lines[i])
+		}
+	}
+	if !strings.Contains(lines[1], `"request_id":"r1"`) {
+		t.Errorf("Expected held entries to keep their fields: %s", lines[1])
+	}
+}
+
+func TestBufferDiscardedWithoutError(t *testing.T) {
+	logger, path := newTestLogger(t, Config{Level: "debug"})
+	reqLog, end := logger.WithBuffer(BufferConfig{})
+	reqLog.Debug().Msg("held")
+	reqLog.Warn().Msg("warn is not held")
+	reqLog.Log().Msg("no level")
+	end()
+	reqLog.Info().Msg("after end")
+	reqLog.Error().Msg("error after end")
+	logger.Close()
+
+	logStr := readLogFile(t, path)
+	for _, msg := range []string{"held", "after end"} {
+		if strings.Contains(logStr, `"message":"`+msg+`"`) {
+			t.Errorf("Expected %q to be discarded: %s", msg, logStr)
+		}
+	}
+	for _, msg := range []string{"warn is not held", "no level", "error after end"} {
+		if !strings.Contains(logStr, `"message":"`+msg+`"`) {
+			t.Errorf("Expected %q to be written: %s", msg, logStr)
+		}
+	}
+	if strings.Index(logStr, "warn is not held") > strings.Index(logStr, "no level") {
+		t.Errorf("Expected the warn entry to be written when logged: %s", logStr)
+	}
+}
+
+func TestBufferSizeAndTTL(t *testing.T) {
+	logger, path := newTestLogger(t, Config{Level: "debug"})
+	reqLog, end := logger.WithBuffer(BufferConfig{Size: 3, TTL: 50 * time.Millisecond})
+	defer end()
+	reqLog.Debug().Msg("expired")
+	time.Sleep(60 * time.Millisecond)
+	for _, msg := range []string{"one", "two", "three", "four"} {
+		reqLog.Debug().Msg(msg)
+	}
+	reqLog.Error().Msg("failed")
+	logger.Close()
+
+	logStr := readLogFile(t, path)
+	if strings.Contains(logStr, "expired") || strings.Contains(logStr, `"one"`) {
+		t.Errorf("Expected expired and overflowing entries to be dropped: %s", logStr)
+	}
+	if strings.Count(logStr, "\n") != 4 {
+		t.Errorf("Expected the 3 newest entries and the error: %s", logStr)
+	}
+}
+
+func TestBufferContextPropagation(t *testing.T) {
+	logger, path := newTestLogger(t, Config{Level: "debug"})
+	sc, _ := ParseTraceparent(testTraceparent)
+	span := &recordingSpan{sc: sc}
+	traced := logger.Ctx(trace.ContextWithSpan(context.Background(), span))
+
+	reqLog, end := traced.WithBuffer(BufferConfig{})
+	defer end()
+	ctx := reqLog.WithContext(context.Background())
+	zerolog.Ctx(ctx).Debug().Msg("from context")
+	zerolog.Ctx(ctx).Error().Msg("context error")
+	logger.Close()
+
+	logStr := readLogFile(t, path)
+	if !strings.Contains(logStr, "from context") || !strings.Contains(logStr, `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`) {
+		t.Errorf("Expected the held entry with trace fields: %s", logStr)
+	}
+	if reqLog.ctx == nil {
+		t.Error("Expected the bound context to carry over to the buffered logger")
+	}
+}
+
+func TestBufferNested(t *testing.T) {
+	logger, path := newTestLogger(t, Config{Level: "debug"})
+	outer, endOuter := logger.WithBuffer(BufferConfig{})
+	inner, endInner := outer.WithBuffer(BufferConfig{})
+	outer.Debug().Msg("outer")
+	inner.Debug().Msg("inner")
+	inner.Error().Msg("inner failed")
+	endInner()
+	endOuter()
+	logger.Close()
+
+	// The inner error flushes the inner buffer into the outer one, where it
+	// triggers the outer flush too
+	logStr := readLogFile(t, path)
+	for _, msg := range []string{"outer", "inner", "inner failed"} {
+		if !strings.Contains(logStr, `"message":"`+msg+`"`) {
+			t.Errorf("Expected %q: %s", msg, logStr)
+		}
+	}
+}
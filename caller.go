@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// CallerFormat selects how the caller field is written
+type CallerFormat int
+
+const (
+	// CallerFull writes the absolute source path and line (default)
+	CallerFull CallerFormat = iota
+	// CallerModule writes the path relative to the main module root, e.g.
+	// "internal/db/store.go:42"; code from other modules keeps its import
+	// path, e.g. "github.com/rs/zerolog/log.go:12"
+	CallerModule
+	// CallerBase writes only the file name and line, e.g. "store.go:42"
+	CallerBase
+	// CallerFunc writes the package and function name with the line, e.g.
+	// "db.(*Store).Get:42"
+	CallerFunc
+)
+
+// CallerFormatFunc formats the caller field from a program counter, source
+// file and line, like zerolog.CallerMarshalFunc
+type CallerFormatFunc func(pc uintptr, file string, line int) string
+
+// callerHookSkipFrames is the number of frames between callerHook.Run and the
+// code that sent the event: Run, Event.msg and Event.Msg (or Msgf, Send)
+const callerHookSkipFrames = 3
+
+// callerHook adds the caller field using a per-logger format. Unlike
+// zerolog's global CallerMarshalFunc, it does not affect other loggers.
+type callerHook struct {
+	skip   int
+	format CallerFormatFunc
+}
+
+// Run implements zerolog.Hook
+func (h callerHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	if pc, file, line, ok := runtime.Caller(callerHookSkipFrames + h.skip); ok {
+		e.Str(zerolog.CallerFieldName, h.format(pc, file, line))
+	}
+}
+
+// withCaller adds caller information to ctx according to cfg
+func withCaller(ctx zerolog.Context, cfg Config) zerolog.Context {
+	if cfg.CallerFormatter == nil && cfg.CallerFormat == CallerFull {
+		if cfg.CallerSkip == 0 {
+			return ctx.Caller()
+		}
+		return ctx.CallerWithSkipFrameCount(zerolog.CallerSkipFrameCount + cfg.CallerSkip)
+	}
+
+	format := cfg.CallerFormatter
+	if format == nil {
+		format = callerFormatter(cfg.CallerFormat)
+	}
+	return ctx.Logger().Hook(callerHook{skip: cfg.CallerSkip, format: format}).With()
+}
+
+// callerFormatter returns the formatter for a built-in CallerFormat
+func callerFormatter(f CallerFormat) CallerFormatFunc {
+	switch f {
+	case CallerModule:
+		return formatCallerModule
+	case CallerBase:
+		return formatCallerBase
+	case CallerFunc:
+		return formatCallerFunc
+	default:
+		return zerolog.CallerMarshalFunc
+	}
+}
+
+// formatCallerBase formats "file.go:42"
+func formatCallerBase(_ uintptr, file string, line int) string {
+	return filepath.Base(file) + ":" + strconv.Itoa(line)
+}
+
+// formatCallerFunc formats "pkg.Function:42"
+func formatCallerFunc(pc uintptr, file string, line int) string {
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return formatCallerBase(pc, file, line)
+	}
+	name := fn.Name()
+	return name[strings.LastIndex(name, "/")+1:] + ":" + strconv.Itoa(line)
+}
+
+// formatCallerModule formats the import path of the file's package joined
+// with the file name, trimmed of the main module path. Import paths come
+// from the function name, so the result does not depend on where or whether
+// -trimpath was used when building.
+func formatCallerModule(pc uintptr, file string, line int) string {
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return formatCallerBase(pc, file, line)
+	}
+	info := moduleInfo()
+
+	pkg := funcPackage(fn.Name())
+	if pkg == "main" && info.mainPackage != "" {
+		pkg = info.mainPackage
+	}
+	path := pkg + "/" + filepath.Base(file)
+	if info.module != "" {
+		if rel, ok := strings.CutPrefix(path, info.module+"/"); ok {
+			path = rel
+		}
+	}
+	return path + ":" + strconv.Itoa(line)
+}
+
+// funcPackage returns the import path part of a fully qualified function
+// name such as "github.com/org/app/db.(*Store).Get"
+func funcPackage(name string) string {
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
+
+// buildModuleInfo holds the module and main package paths of the binary
+type buildModuleInfo struct {
+	module      string // Main module path, e.g. "github.com/org/app"
+	mainPackage string // Import path of package main, e.g. "github.com/org/app/cmd/app"
+}
+
+// moduleInfo reads the build info once
+var moduleInfo = sync.OnceValue(func() buildModuleInfo {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return buildModuleInfo{}
+	}
+	return buildModuleInfo{module: bi.Main.Path, mainPackage: bi.Path}
+})
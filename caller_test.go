@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// logCaller logs one entry through logf and returns the caller field along
+// with the line logf was called from
+func logCaller(t *testing.T, cfg Config, logf func(l *Logger)) (string, int) {
+	t.Helper()
+	cfg.LogDir = t.TempDir()
+	cfg.Filename = "caller.log"
+
+	logger := New(cfg)
+	_, _, line, _ := runtime.Caller(1)
+	logf(logger)
+	logger.Close()
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(readLogFile(t, filepath.Join(cfg.LogDir, cfg.Filename))), &entry); err != nil {
+		t.Fatalf("Failed to decode log entry: %v", err)
+	}
+	caller, _ := entry["caller"].(string)
+	return caller, line
+}
+
+// logViaHelper stands in for an application's logging wrapper
+func logViaHelper(l *Logger) {
+	l.Info().Msg("from helper")
+}
+
+func TestCallerFormats(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+
+	tests := []struct {
+		name   string
+		format CallerFormat
+		want   func(line int) string
+	}{
+		{"full", CallerFull, func(line int) string { return file + ":" + strconv.Itoa(line) }},
+		{"module", CallerModule, func(line int) string { return "caller_test.go:" + strconv.Itoa(line) }},
+		{"base", CallerBase, func(line int) string { return "caller_test.go:" + strconv.Itoa(line) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caller, line := logCaller(t, Config{CallerFormat: tt.format}, func(l *Logger) { l.Info().Msg("format") })
+			if want := tt.want(line); caller != want {
+				t.Errorf("Expected caller %q, got %q", want, caller)
+			}
+		})
+	}
+
+	// Closures are named after their enclosing function
+	caller, line := logCaller(t, Config{CallerFormat: CallerFunc}, func(l *Logger) { l.Info().Msg("func") })
+	if !strings.HasPrefix(caller, "go-logger.TestCallerFormats.") || !strings.HasSuffix(caller, ":"+strconv.Itoa(line)) {
+		t.Errorf("Expected package.Function caller, got %q", caller)
+	}
+}
+
+func TestCallerFormatter(t *testing.T) {
+	cfg := Config{
+		CallerFormat: CallerModule, // Overridden by the formatter
+		CallerFormatter: func(_ uintptr, file string, line int) string {
+			return "custom:" + filepath.Base(file) + "#" + strconv.Itoa(line)
+		},
+	}
+	caller, line := logCaller(t, cfg, func(l *Logger) { l.Info().Msgf("%s", "custom") })
+	if want := "custom:caller_test.go#" + strconv.Itoa(line); caller != want {
+		t.Errorf("Expected caller %q, got %q", want, caller)
+	}
+}
+
+func TestCallerSkip(t *testing.T) {
+	for _, format := range []CallerFormat{CallerFull, CallerBase} {
+		caller, line := logCaller(t, Config{CallerFormat: format, CallerSkip: 1}, func(l *Logger) { logViaHelper(l) })
+		if !strings.HasSuffix(caller, "caller_test.go:"+strconv.Itoa(line)) {
+			t.Errorf("Format %d: expected the helper's caller at line %d, got %q", format, line, caller)
+		}
+	}
+}
+
+func TestCallerFormatDisabled(t *testing.T) {
+	caller, _ := logCaller(t, Config{DisableCaller: true, CallerFormat: CallerBase}, func(l *Logger) { l.Info().Send() })
+	if caller != "" {
+		t.Errorf("Expected no caller when DisableCaller is set, got %q", caller)
+	}
+}
+
+func TestFuncPackage(t *testing.T) {
+	tests := map[string]string{
+		"github.com/org/app/internal/db.(*Store).Get": "github.com/org/app/internal/db",
+		"github.com/org/app.Run.func1":                "github.com/org/app",
+		"main.main":                                   "main",
+		"net/http.(*conn).serve":                      "net/http",
+		"github.com/org/app.Map[...]":                 "github.com/org/app",
+	}
+	for name, want := range tests {
+		if got := funcPackage(name); got != want {
+			t.Errorf("funcPackage(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// SuppressedFieldName is the field holding the number of entries a call site
+// limited by Every skipped since its previous entry
+const SuppressedFieldName = "suppressed"
+
+// callSites holds the call site state of Logger values not created by New.
+// Loggers from New keep their own, so limits are not shared between them.
+var callSites sync.Map
+
+// callSite is the limit state of one call site. It is only updated with
+// atomic operations.
+type callSite struct {
+	every      time.Duration // Minimum gap between entries, for Every
+	n          uint64        // Entries allowed in total, for FirstN and Once
+	next       atomic.Int64  // Unix nanoseconds at which Every allows the next entry
+	used       atomic.Uint64 // Entries allowed so far, for FirstN and Once
+	suppressed atomic.Uint64 // Entries skipped by Every since the last one
+}
+
+// allow reports whether the call site may write another entry
+func (s *callSite) allow() bool {
+	if s.every == 0 {
+		return s.used.Load() < s.n && s.used.Add(1) <= s.n
+	}
+	now := time.Now().UnixNano()
+	next := s.next.Load()
+	if now >= next && s.next.CompareAndSwap(next, now+int64(s.every)) {
+		return true
+	}
+	s.suppressed.Add(1)
+	return false
+}
+
+// SiteLogger creates entries limited per call site. It is returned by
+// Logger.Every, Logger.FirstN and Logger.Once and is meant to be used in the
+// same expression:
+//
+//	log.Every(time.Minute).Warn().Msg("Cache is cold")
+type SiteLogger struct {
+	l    *Logger
+	site *callSite
+}
+
+// Every limits the calling line to one entry per d. The next entry carries a
+// suppressed field counting the entries skipped in between. Limits are kept
+// per logger created by New and shared with the loggers derived from it.
+func (l *Logger) Every(d time.Duration) SiteLogger {
+	return l.site(max(d, 1), 0)
+}
+
+// FirstN limits the calling line to its first n entries
+func (l *Logger) FirstN(n int) SiteLogger {
+	return l.site(0, uint64(max(n, 0)))
+}
+
+// Once limits the calling line to a single entry, e.g. for deprecation notices
+func (l *Logger) Once() SiteLogger {
+	return l.site(0, 1)
+}
+
+// site returns the SiteLogger of the line that called Every, FirstN or Once,
+// skipping the same extra frames as the caller field (Config.CallerSkip)
+func (l *Logger) site(every time.Duration, n uint64) SiteLogger {
+	var pc [1]uintptr
+	runtime.Callers(3+l.callerSkip, pc[:])
+	sites := l.sites
+	if sites == nil {
+		sites = &callSites
+	}
+	// Sites are keyed by pc alone, so the first limit used at a site applies
+	// and computed limits such as Every(backoff) do not grow the map
+	s, ok := sites.Load(pc[0])
+	if !ok {
+		s, _ = sites.LoadOrStore(pc[0], &callSite{every: every, n: n})
+	}
+	return SiteLogger{l: l, site: s.(*callSite)}
+}
+
+// Debug starts a debug entry, or returns nil if the call site is over its limit
+func (s SiteLogger) Debug() *zerolog.Event {
+	return s.event(zerolog.DebugLevel)
+}
+
+// Info starts an info entry, or returns nil if the call site is over its limit
+func (s SiteLogger) Info() *zerolog.Event {
+	return s.event(zerolog.InfoLevel)
+}
+
+// Warn starts a warn entry, or returns nil if the call site is over its limit
+func (s SiteLogger) Warn() *zerolog.Event {
+	return s.event(zerolog.WarnLevel)
+}
+
+// Error starts an error entry, or returns nil if the call site is over its limit
+func (s SiteLogger) Error() *zerolog.Event {
+	return s.event(zerolog.ErrorLevel)
+}
+
+// event starts an entry at level if the limit allows it. Entries disabled by
+// the level do not count against the limit, and no event is allocated for
+// suppressed ones.
+func (s SiteLogger) event(level zerolog.Level) *zerolog.Event {
+	if level < s.l.GetLevel() || level < zerolog.GlobalLevel() || !s.site.allow() {
+		return nil
+	}
+	e := s.l.WithLevel(level)
+	if n := s.site.suppressed.Swap(0); n > 0 {
+		e.Uint64(SuppressedFieldName, n)
+	}
+	return e
+}
@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOnceAndFirstN(t *testing.T) {
+	logger, path := newTestLogger(t, Config{})
+	for range 10 {
+		logger.Once().Warn().Msg("deprecated option")
+		logger.FirstN(3).Info().Msg("warming up")
+	}
+	logger.Once().Warn().Msg("deprecated option") // another call site
+	logger.Close()
+
+	logStr := readLogFile(t, path)
+	if got := strings.Count(logStr, "deprecated option"); got != 2 {
+		t.Errorf("Expected one entry per Once call site, got %d", got)
+	}
+	if got := strings.Count(logStr, "warming up"); got != 3 {
+		t.Errorf("Expected 3 entries from FirstN, got %d", got)
+	}
+	if strings.Contains(logStr, SuppressedFieldName) {
+		t.Errorf("Did not expect suppressed counts from Once or FirstN: %s", logStr)
+	}
+}
+
+func TestSiteLimitsPerLogger(t *testing.T) {
+	first, firstPath := newTestLogger(t, Config{})
+	second, secondPath := newTestLogger(t, Config{})
+	notice := func(l *Logger, n int) { l.FirstN(n).Warn().Msg("deprecated option") }
+	for range 3 {
+		notice(first, 1)
+		notice(first.WithField("req", 1), 1) // Derived loggers share the limit
+		notice(second, 1)
+		notice(second, 2) // The first limit used at a site applies
+	}
+	first.Close()
+	second.Close()
+
+	if got := strings.Count(readLogFile(t, firstPath), "deprecated option"); got != 1 {
+		t.Errorf("Expected 1 entry from the first logger, got %d", got)
+	}
+	if got := strings.Count(readLogFile(t, secondPath), "deprecated option"); got != 1 {
+		t.Errorf("Expected 1 entry from the second logger, got %d", got)
+	}
+}
+
+func TestSiteComputedLimit(t *testing.T) {
+	logger, path := newTestLogger(t, Config{})
+	for i := range 100 {
+		logger.Every(time.Duration(i+1) * time.Hour).Warn().Msg("retrying")
+	}
+	logger.Close()
+
+	sites := 0
+	logger.sites.Range(func(_, _ interface{}) bool { sites++; return true })
+	if sites != 1 {
+		t.Errorf("Expected one call site for computed limits, got %d", sites)
+	}
+	if got := strings.Count(readLogFile(t, path), "retrying"); got != 1 {
+		t.Errorf("Expected 1 entry under the first limit, got %d", got)
+	}
+}
+
+func TestEveryReportsSuppressed(t *testing.T) {
+	logger, path := newTestLogger(t, Config{})
+	tick := func() { logger.Every(50 * time.Millisecond).Warn().Msg("queue full") }
+	for range 5 {
+		tick()
+	}
+	time.Sleep(60 * time.Millisecond)
+	tick()
+	tick()
+	logger.Close()
+
+	lines := strings.Split(strings.TrimSpace(readLogFile(t, path)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 entries, got %d: %v", len(lines), lines)
+	}
+	if strings.Contains(lines[0], SuppressedFieldName) || !strings.Contains(lines[1], `"suppressed":4`) {
+		t.Errorf("Expected the second entry to report 4 suppressed entries: %v", lines)
+	}
+}
+
+func TestSiteLimitIgnoresDisabledLevels(t *testing.T) {
+	logger, path := newTestLogger(t, Config{Level: "info"})
+	emit := func(debug bool) {
+		s := logger.Once()
+		if debug {
+			s.Debug().Msg("once")
+		} else {
+			s.Info().Msg("once")
+		}
+	}
+	emit(true)
+	emit(false)
+	emit(false)
+	logger.Close()
+
+	if got := strings.Count(readLogFile(t, path), `"message":"once"`); got != 1 {
+		t.Errorf("Expected the info entry to be written once, got %d", got)
+	}
+}
+
+// warnOnce is a logging helper whose callers are the call sites when
+// CallerSkip is 1
+func warnOnce(l *Logger, msg string) {
+	l.Once().Warn().Msg(msg)
+}
+
+func TestSiteHonorsCallerSkip(t *testing.T) {
+	for _, tt := range []struct {
+		skip int
+		want int
+	}{{0, 1}, {1, 2}} {
+		logger, path := newTestLogger(t, Config{CallerSkip: tt.skip})
+		warnOnce(logger, "helper")
+		warnOnce(logger, "helper")
+		logger.Close()
+		if got := strings.Count(readLogFile(t, path), `"message":"helper"`); got != tt.want {
+			t.Errorf("CallerSkip %d: expected %d entries, got %d", tt.skip, tt.want, got)
+		}
+	}
+}
+
+func TestFirstNConcurrent(t *testing.T) {
+	logger, path := newTestLogger(t, Config{})
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				logger.FirstN(5).Info().Msg("concurrent")
+			}
+		}()
+	}
+	wg.Wait()
+	logger.Close()
+
+	if got := strings.Count(readLogFile(t, path), "concurrent"); got != 5 {
+		t.Errorf("Expected exactly 5 entries, got %d", got)
+	}
+}
+
+func BenchmarkEverySuppressed(b *testing.B) {
+	logger := New(Config{LogDir: b.TempDir(), Filename: "bench.log"})
+	defer logger.Close()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			logger.Every(time.Hour).Info().Msg("suppressed")
+		}
+	})
+}
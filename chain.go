@@ -0,0 +1,527 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultCheckpointEvery is the number of entries between signed checkpoints
+	defaultCheckpointEvery = 1000
+
+	// chainRecordPrefix starts every header and checkpoint line
+	chainRecordPrefix = `{"logchain":`
+
+	// chainHashPrefix precedes the hex hash that ends every chained entry
+	chainHashPrefix = `,"hash":"`
+)
+
+// HashChainConfig makes the log file tamper-evident. Every entry gets a "seq"
+// number and a "hash" field holding SHA-256 over the previous entry's hash and
+// the entry itself, so editing, removing or reordering entries breaks the
+// chain. Each new file starts with a header that links to the last hash of the
+// file before it.
+//
+// Anyone who can write the log files can also recompute a bare hash chain;
+// signed checkpoints rule that out for every entry they cover. Checkpoints are
+// written every CheckpointEvery entries, before each rotation and on Close.
+type HashChainConfig struct {
+	Enabled         bool
+	SigningKey      ed25519.PrivateKey // Signs checkpoints (default: nil, no checkpoints)
+	CheckpointEvery int                // Entries between checkpoints (default: 1000)
+}
+
+// chainRecord is a header or checkpoint line. Headers carry NextSeq and Prev,
+// checkpoints carry Seq, Hash and Sig.
+type chainRecord struct {
+	Kind    string `json:"logchain"`
+	Time    string `json:"time"`
+	File    string `json:"file,omitempty"`
+	NextSeq uint64 `json:"next_seq,omitempty"`
+	Prev    string `json:"prev,omitempty"`
+	Seq     uint64 `json:"seq,omitempty"`
+	Hash    string `json:"hash,omitempty"`
+	Sig     string `json:"sig,omitempty"`
+}
+
+// chainWriter adds sequence numbers and chained hashes to entries written to
+// a rotatingWriter. It is the rotatingWriter's only caller, so the header and
+// footer callbacks run with c.mu held.
+type chainWriter struct {
+	mu     sync.Mutex
+	rw     *rotatingWriter
+	name   string
+	key    ed25519.PrivateKey
+	every  uint64
+	seq    uint64 // Sequence number of the last entry written
+	prev   []byte // Hash of the last entry written
+	signed uint64 // Sequence number covered by the last checkpoint
+}
+
+// newChainWriter resumes the chain from the newest existing file in root and
+// opens a rotatingWriter that writes chain headers and checkpoints
+func newChainWriter(root *os.Root, name string, opts rotateOptions, cfg HashChainConfig) (*chainWriter, error) {
+	c := &chainWriter{name: name, key: cfg.SigningKey, every: defaultCheckpointEvery}
+	if cfg.CheckpointEvery > 0 {
+		c.every = uint64(cfg.CheckpointEvery)
+	}
+	// Encrypted files are never appended to, and their cut-off chunks are
+	// skipped when reading
+	if opts.encrypt == nil {
+		if err := trimPartialLine(root, name); err != nil {
+			return nil, err
+		}
+	}
+	c.seq, c.prev = resumeChain(root, name, opts.encrypt)
+	c.signed = c.seq
+
+	opts.header, opts.footer = c.header, c.footer
+	rw, err := newRotatingWriter(root, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.rw = rw
+	return c, nil
+}
+
+// Write implements io.Writer, chaining each line of p
+func (c *chainWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		seq := c.seq + 1
+		body := chainBody(line, seq)
+		hash := chainHash(c.prev, body)
+		out := append(body[:len(body)-1], chainHashPrefix...)
+		out = hex.AppendEncode(out, hash)
+		out = append(out, "\"}\n"...)
+
+		// Commit only after the write, so a header written by a rotation
+		// inside it still links to the previous entry
+		if _, err := c.rw.Write(out); err != nil {
+			return 0, err
+		}
+		c.seq, c.prev = seq, hash
+
+		if c.key != nil && c.seq-c.signed >= c.every {
+			if _, err := c.rw.Write(c.checkpoint()); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// Rotate starts a new file, signing the end of the current one
+func (c *chainWriter) Rotate() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rw.Rotate()
+}
+
+// Sync flushes the current file to stable storage
+func (c *chainWriter) Sync() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rw.Sync()
+}
+
+// Close signs the end of the current file and closes it
+func (c *chainWriter) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rw.Close()
+}
+
+// header links a new file to the last hash of the previous one
+func (c *chainWriter) header() []byte {
+	return marshalChainRecord(chainRecord{
+		Kind:    "header",
+		File:    c.name,
+		NextSeq: c.seq + 1,
+		Prev:    hex.EncodeToString(c.prev),
+	})
+}
+
+// footer signs any entries not yet covered by a checkpoint
+func (c *chainWriter) footer() []byte {
+	if c.key == nil || c.seq == c.signed {
+		return nil
+	}
+	return c.checkpoint()
+}
+
+// checkpoint signs the current sequence number and hash
+func (c *chainWriter) checkpoint() []byte {
+	c.signed = c.seq
+	return marshalChainRecord(chainRecord{
+		Kind: "checkpoint",
+		Seq:  c.seq,
+		Hash: hex.EncodeToString(c.prev),
+		Sig:  base64.StdEncoding.EncodeToString(ed25519.Sign(c.key, checkpointMessage(c.seq, c.prev))),
+	})
+}
+
+// marshalChainRecord encodes r as a log line stamped with the current time
+func marshalChainRecord(r chainRecord) []byte {
+	r.Time = time.Now().UTC().Format(time.RFC3339)
+	b, _ := json.Marshal(r)
+	return append(b, '\n')
+}
+
+// chainBody appends the "seq" field to a JSON entry. Anything that is not a
+// JSON object, or could pass for a chain record, is wrapped in a "raw" field.
+func chainBody(line []byte, seq uint64) []byte {
+	var b []byte
+	switch {
+	case string(line) == "{}":
+		b = append(b, '{')
+	case len(line) > 2 && line[0] == '{' && line[len(line)-1] == '}' && !bytes.HasPrefix(line, []byte(chainRecordPrefix)):
+		b = append(b, line[:len(line)-1]...)
+		b = append(b, ',')
+	default:
+		raw, _ := json.Marshal(string(line))
+		b = append(b, `{"raw":`...)
+		b = append(b, raw...)
+		b = append(b, ',')
+	}
+	b = append(b, `"seq":`...)
+	b = strconv.AppendUint(b, seq, 10)
+	return append(b, '}')
+}
+
+// chainHash returns SHA-256(prev || body)
+func chainHash(prev, body []byte) []byte {
+	h := sha256.New()
+	h.Write(prev)
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// checkpointMessage is the byte string signed by a checkpoint
+func checkpointMessage(seq uint64, hash []byte) []byte {
+	return fmt.Appendf(nil, "logchain checkpoint %d %x", seq, hash)
+}
+
+// parseChainEntry splits a chained entry into the hashed body, its sequence
+// number and its hash
+func parseChainEntry(line []byte) (body []byte, seq uint64, hash []byte, ok bool) {
+	suffixLen := len(chainHashPrefix) + 2*sha256.Size + len(`"}`)
+	if len(line) < suffixLen || !bytes.HasSuffix(line, []byte(`"}`)) {
+		return nil, 0, nil, false
+	}
+	cut := len(line) - suffixLen
+	if string(line[cut:cut+len(chainHashPrefix)]) != chainHashPrefix {
+		return nil, 0, nil, false
+	}
+	hash, err := hex.DecodeString(string(line[cut+len(chainHashPrefix) : len(line)-2]))
+	if err != nil {
+		return nil, 0, nil, false
+	}
+	body = append(slices.Clip(line[:cut]), '}')
+
+	i := bytes.LastIndex(body, []byte(`"seq":`))
+	if i < 1 || (body[i-1] != ',' && body[i-1] != '{') {
+		return nil, 0, nil, false
+	}
+	seq, err = strconv.ParseUint(string(body[i+len(`"seq":`):len(body)-1]), 10, 64)
+	if err != nil {
+		return nil, 0, nil, false
+	}
+	return body, seq, hash, true
+}
+
+// resumeChain returns the last sequence number and hash in the newest
+// non-empty file among name and its backups, decrypting them with enc if
+// set. A fresh chain starts at zero; so does one whose last line cannot be
+// parsed, which verification then reports as a break.
+func resumeChain(root *os.Root, name string, enc *encryptionKeys) (uint64, []byte) {
+	genesis := make([]byte, sha256.Size)
+
+	candidates := []string{name}
+	if dir, err := root.Open("."); err == nil {
+		entries, _ := dir.ReadDir(-1)
+		dir.Close()
+		var backups []string
+		for _, e := range entries {
+			if _, ok := backupTime(name, e.Name()); ok {
+				backups = append(backups, e.Name())
+			}
+		}
+		// Backup names sort chronologically; newest first
+		slices.Sort(backups)
+		slices.Reverse(backups)
+		candidates = append(candidates, backups...)
+	}
+
+	for _, candidate := range candidates {
+		line, err := readLastLine(root, candidate, enc)
+		if err != nil || len(line) == 0 {
+			continue
+		}
+		if seq, hash, ok := chainState(line); ok {
+			return seq, hash
+		}
+		return 0, genesis
+	}
+	return 0, genesis
+}
+
+// chainState returns the sequence number and hash the chain is at after line
+func chainState(line []byte) (uint64, []byte, bool) {
+	if !bytes.HasPrefix(line, []byte(chainRecordPrefix)) {
+		_, seq, hash, ok := parseChainEntry(line)
+		return seq, hash, ok
+	}
+	var r chainRecord
+	if err := json.Unmarshal(line, &r); err != nil {
+		return 0, nil, false
+	}
+	switch r.Kind {
+	case "header":
+		prev, err := hex.DecodeString(r.Prev)
+		return r.NextSeq - 1, prev, err == nil && len(prev) == sha256.Size && r.NextSeq > 0
+	case "checkpoint":
+		hash, err := hex.DecodeString(r.Hash)
+		return r.Seq, hash, err == nil && len(hash) == sha256.Size
+	}
+	return 0, nil, false
+}
+
+// trimPartialLine truncates name after its last newline. A line without one
+// was cut off by a crash; appending to it would glue the next entry onto it
+// and restart the sequence, so the chain resumes from the last whole line.
+func trimPartialLine(root *os.Root, name string) error {
+	f, err := root.OpenFile(name, os.O_RDWR, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+
+	const chunk = 64 * 1024
+	for end := info.Size(); end > 0; {
+		n := min(chunk, end)
+		buf := make([]byte, n)
+		if _, err := f.ReadAt(buf, end-n); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if i := bytes.LastIndexByte(buf, '\n'); i >= 0 {
+			if keep := end - n + int64(i) + 1; keep < info.Size() {
+				return f.Truncate(keep)
+			}
+			return nil
+		}
+		end -= n
+	}
+	// Not a single whole line
+	return f.Truncate(0)
+}
+
+// readLastLine returns the last line of name, without its newline, reading
+// backwards so large files are not loaded whole. Encrypted files are
+// decrypted from the start; a file cut off by a crash yields the last line
+// of its intact chunks.
+func readLastLine(root *os.Root, name string, enc *encryptionKeys) ([]byte, error) {
+	f, err := root.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if enc != nil {
+		var last []byte
+		br := bufio.NewReader(NewDecryptReader(f, enc.keys))
+		for {
+			line, err := br.ReadBytes('\n')
+			if err != nil {
+				return last, nil
+			}
+			last = line[:len(line)-1]
+		}
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	const chunk = 64 * 1024
+	var tail []byte
+	for off := info.Size(); off > 0; {
+		n := min(chunk, off)
+		off -= n
+		buf := make([]byte, n, n+int64(len(tail)))
+		if _, err := f.ReadAt(buf, off); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		tail = append(buf, tail...)
+		line := bytes.TrimSuffix(tail, []byte("\n"))
+		if i := bytes.LastIndexByte(line, '\n'); i >= 0 {
+			return line[i+1:], nil
+		}
+		if off == 0 {
+			return line, nil
+		}
+	}
+	return nil, nil
+}
+
+// ChainBreak describes the first place where a hash chain fails to verify
+type ChainBreak struct {
+	File   string
+	Line   int
+	Seq    uint64 // Sequence number expected at the break
+	Reason string
+}
+
+// Error implements error
+func (b *ChainBreak) Error() string {
+	return fmt.Sprintf("%s:%d: chain broken at seq %d: %s", b.File, b.Line, b.Seq, b.Reason)
+}
+
+// ChainVerifier checks log files written with HashChain enabled. Pass the
+// files to Verify oldest first, the active file last.
+type ChainVerifier struct {
+	PublicKey ed25519.PublicKey // Verifies checkpoint signatures; nil skips them
+
+	started bool
+	first   uint64
+	seq     uint64
+	prev    []byte
+	signed  uint64
+}
+
+// Verify checks the next file in the chain. It returns a *ChainBreak for the
+// first line that does not continue the chain, or the error from reading r.
+func (v *ChainVerifier) Verify(name string, r io.Reader) error {
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if len(line) == 0 && errors.Is(err, io.EOF) {
+			if n == 1 {
+				return v.breakAt(name, n, "file has no chain header")
+			}
+			return nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if err != nil {
+			return v.breakAt(name, n, "incomplete final line")
+		}
+		if reason := v.line(line[:len(line)-1], n == 1); reason != "" {
+			return v.breakAt(name, n, reason)
+		}
+	}
+}
+
+// line checks one line and returns the reason it breaks the chain, if any
+func (v *ChainVerifier) line(line []byte, first bool) string {
+	if !bytes.HasPrefix(line, []byte(chainRecordPrefix)) {
+		if first {
+			return "file does not start with a chain header"
+		}
+		body, seq, hash, ok := parseChainEntry(line)
+		if !ok {
+			return "entry is not hash-chained"
+		}
+		if seq != v.seq+1 {
+			return fmt.Sprintf("sequence gap: expected %d, got %d", v.seq+1, seq)
+		}
+		if !bytes.Equal(hash, chainHash(v.prev, body)) {
+			return "hash mismatch, entry was modified"
+		}
+		v.seq, v.prev = seq, hash
+		return ""
+	}
+
+	var r chainRecord
+	if err := json.Unmarshal(line, &r); err != nil {
+		return "malformed chain record"
+	}
+	switch r.Kind {
+	case "header":
+		prev, err := hex.DecodeString(r.Prev)
+		if err != nil || len(prev) != sha256.Size || r.NextSeq == 0 {
+			return "malformed chain header"
+		}
+		if !first {
+			return "chain header in the middle of a file"
+		}
+		if !v.started {
+			// The oldest file's header is the trusted starting point
+			v.started, v.first, v.seq, v.prev = true, r.NextSeq, r.NextSeq-1, prev
+			v.signed = v.seq
+			return ""
+		}
+		if r.NextSeq != v.seq+1 {
+			return fmt.Sprintf("file does not continue the previous one: expected seq %d, header has %d", v.seq+1, r.NextSeq)
+		}
+		if !bytes.Equal(prev, v.prev) {
+			return "header does not link to the last hash of the previous file"
+		}
+		return ""
+	case "checkpoint":
+		if first {
+			return "file does not start with a chain header"
+		}
+		if r.Seq != v.seq || r.Hash != hex.EncodeToString(v.prev) {
+			return "checkpoint does not match the chain"
+		}
+		if v.PublicKey != nil {
+			sig, err := base64.StdEncoding.DecodeString(r.Sig)
+			if err != nil || !ed25519.Verify(v.PublicKey, checkpointMessage(v.seq, v.prev), sig) {
+				return "invalid checkpoint signature"
+			}
+			v.signed = v.seq
+		}
+		return ""
+	}
+	return "unknown chain record " + strconv.Quote(r.Kind)
+}
+
+// breakAt builds the ChainBreak for line n of name
+func (v *ChainVerifier) breakAt(name string, n int, reason string) *ChainBreak {
+	return &ChainBreak{File: name, Line: n, Seq: v.seq + 1, Reason: reason}
+}
+
+// FirstSeq returns the sequence number the verified chain starts at. It is
+// above 1 when older files were removed by rotation.
+func (v *ChainVerifier) FirstSeq() uint64 {
+	return v.first
+}
+
+// LastSeq returns the sequence number of the last verified entry
+func (v *ChainVerifier) LastSeq() uint64 {
+	return v.seq
+}
+
+// LastSigned returns the sequence number of the last entry covered by a
+// valid checkpoint signature. Entries after it could have been rewritten or
+// cut off by someone able to write the files.
+func (v *ChainVerifier) LastSigned() uint64 {
+	return v.signed
+}
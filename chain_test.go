@@ -0,0 +1,327 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chainFiles returns the log file and its backups in tmpDir, oldest first
+func chainFiles(t *testing.T, dir, name string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir returned error: %v", err)
+	}
+	var backups []string
+	for _, e := range entries {
+		if _, ok := backupTime(name, e.Name()); ok {
+			backups = append(backups, filepath.Join(dir, e.Name()))
+		}
+	}
+	slices.Sort(backups)
+	return append(backups, filepath.Join(dir, name))
+}
+
+// verifyChain runs a ChainVerifier over files in order
+func verifyChain(t *testing.T, pub ed25519.PublicKey, files ...string) (*ChainVerifier, error) {
+	t.Helper()
+	v := &ChainVerifier{PublicKey: pub}
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("ReadFile returned error: %v", err)
+		}
+		if err := v.Verify(name, bytes.NewReader(data)); err != nil {
+			return v, err
+		}
+	}
+	return v, nil
+}
+
+// expectBreak asserts that err is a ChainBreak whose reason contains reason
+func expectBreak(t *testing.T, err error, reason string) {
+	t.Helper()
+	var b *ChainBreak
+	if !errors.As(err, &b) {
+		t.Fatalf("Expected ChainBreak containing %q, got %v", reason, err)
+	}
+	if !strings.Contains(b.Reason, reason) {
+		t.Errorf("Expected break reason containing %q, got %v", reason, b)
+	}
+}
+
+// rotateChain rotates the logger's chained file
+func rotateChain(t *testing.T, l *Logger) {
+	t.Helper()
+	// Backup names have millisecond resolution
+	time.Sleep(2 * time.Millisecond)
+	if err := l.fileWriter.(*chainWriter).Rotate(); err != nil {
+		t.Fatalf("Rotate returned error: %v", err)
+	}
+}
+
+func TestHashChainEntries(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "chain.log", HashChain: HashChainConfig{Enabled: true}})
+	for i := 0; i < 3; i++ {
+		logger.Info().Int("i", i).Msg("chained")
+	}
+	logger.Close()
+
+	lines := strings.Split(strings.TrimSpace(readLogFile(t, filepath.Join(tmpDir, "chain.log"))), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected header and 3 entries, got %d lines", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], `{"logchain":"header"`) || !strings.Contains(lines[0], `"next_seq":1,"prev":"`+strings.Repeat("0", 64)+`"`) {
+		t.Errorf("Unexpected header: %s", lines[0])
+	}
+	if !strings.Contains(lines[3], `"message":"chained","seq":3,"hash":"`) {
+		t.Errorf("Expected seq and hash appended to entry: %s", lines[3])
+	}
+
+	v, err := verifyChain(t, nil, filepath.Join(tmpDir, "chain.log"))
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if v.FirstSeq() != 1 || v.LastSeq() != 3 {
+		t.Errorf("Expected seq 1-3, got %d-%d", v.FirstSeq(), v.LastSeq())
+	}
+}
+
+func TestHashChainAcrossRotation(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "chain.log", HashChain: HashChainConfig{Enabled: true}})
+	logger.Info().Msg("first file")
+	rotateChain(t, logger)
+	logger.Info().Msg("second file")
+	rotateChain(t, logger)
+	logger.Info().Msg("third file")
+	logger.Close()
+
+	files := chainFiles(t, tmpDir, "chain.log")
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 files, got %v", files)
+	}
+	v, err := verifyChain(t, nil, files...)
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if v.LastSeq() != 3 {
+		t.Errorf("Expected chain to reach seq 3, got %d", v.LastSeq())
+	}
+	if !strings.Contains(readLogFile(t, files[2]), `"next_seq":3`) {
+		t.Error("Expected the new file's header to continue the sequence")
+	}
+
+	// A missing file in the middle breaks the link between its neighbours
+	_, err = verifyChain(t, nil, files[0], files[2])
+	expectBreak(t, err, "does not continue the previous one")
+
+	// Verification may start at any file, as after backups were pruned
+	v, err = verifyChain(t, nil, files[1:]...)
+	if err != nil || v.FirstSeq() != 2 {
+		t.Errorf("Expected chain to verify from seq 2, got first %d, err %v", v.FirstSeq(), err)
+	}
+}
+
+func TestHashChainResumesAfterRestart(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := Config{LogDir: tmpDir, Filename: "chain.log", HashChain: HashChainConfig{Enabled: true}}
+
+	logger := New(cfg)
+	logger.Info().Msg("before restart")
+	logger.Close()
+
+	logger = New(cfg)
+	logger.Info().Msg("after restart")
+	rotateChain(t, logger)
+	logger.Close()
+
+	// The active file is empty apart from its header, so a third start
+	// resumes from the header
+	logger = New(cfg)
+	logger.Info().Msg("after second restart")
+	logger.Close()
+
+	v, err := verifyChain(t, nil, chainFiles(t, tmpDir, "chain.log")...)
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if v.LastSeq() != 3 {
+		t.Errorf("Expected chain to reach seq 3, got %d", v.LastSeq())
+	}
+}
+
+func TestHashChainResumesAfterPartialLine(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "chain.log")
+	cfg := Config{LogDir: tmpDir, Filename: "chain.log", HashChain: HashChainConfig{Enabled: true}}
+
+	logger := New(cfg)
+	logger.Info().Msg("one")
+	logger.Info().Msg("two")
+	logger.Close()
+
+	// A crash in the middle of a write leaves a line without its newline
+	f, _ := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString(`{"level":"info","message":"cut o`)
+	f.Close()
+
+	logger = New(cfg)
+	logger.Info().Msg("three")
+	logger.Close()
+
+	logStr := readLogFile(t, logPath)
+	if strings.Contains(logStr, "cut o") {
+		t.Errorf("Expected the partial line to be removed: %s", logStr)
+	}
+	v, err := verifyChain(t, nil, chainFiles(t, tmpDir, "chain.log")...)
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if v.LastSeq() != 3 {
+		t.Errorf("Expected the chain to continue at seq 3, got %d", v.LastSeq())
+	}
+
+	// A file holding only a partial header starts over
+	os.WriteFile(logPath, []byte(`{"logchain":"hea`), 0600)
+	root, err := os.OpenRoot(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	if err := trimPartialLine(root, "chain.log"); err != nil {
+		t.Fatalf("trimPartialLine returned error: %v", err)
+	}
+	if info, _ := os.Stat(logPath); info.Size() != 0 {
+		t.Errorf("Expected an empty file, got %d bytes", info.Size())
+	}
+}
+
+func TestHashChainDetectsTampering(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "chain.log")
+
+	logger := New(Config{LogDir: tmpDir, Filename: "chain.log", HashChain: HashChainConfig{Enabled: true}})
+	for _, msg := range []string{"alpha", "bravo", "charlie", "delta"} {
+		logger.Info().Msg(msg)
+	}
+	logger.Close()
+
+	original := readLogFile(t, logPath)
+	lines := strings.SplitAfter(original, "\n")
+	lines = lines[:len(lines)-1]
+
+	tests := []struct {
+		name   string
+		edit   func([]string) []string
+		reason string
+	}{
+		{"modified entry", func(l []string) []string {
+			l[2] = strings.Replace(l[2], "bravo", "brave", 1)
+			return l
+		}, "hash mismatch"},
+		{"deleted entry", func(l []string) []string {
+			return slices.Delete(l, 2, 3)
+		}, "sequence gap: expected 2, got 3"},
+		{"reordered entries", func(l []string) []string {
+			l[1], l[2] = l[2], l[1]
+			return l
+		}, "sequence gap"},
+		{"removed header", func(l []string) []string {
+			return l[1:]
+		}, "does not start with a chain header"},
+		{"cut final line", func(l []string) []string {
+			l[len(l)-1] = strings.TrimSuffix(l[len(l)-1], "\n")
+			return l
+		}, "incomplete final line"},
+		{"entry without hash", func(l []string) []string {
+			return slices.Insert(l, 3, `{"level":"info","message":"injected"}`+"\n")
+		}, "not hash-chained"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := strings.Join(tt.edit(slices.Clone(lines)), "")
+			err := (&ChainVerifier{}).Verify("chain.log", strings.NewReader(tampered))
+			expectBreak(t, err, tt.reason)
+		})
+	}
+}
+
+func TestHashChainCheckpoints(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "chain.log")
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+
+	logger := New(Config{
+		LogDir:    tmpDir,
+		Filename:  "chain.log",
+		HashChain: HashChainConfig{Enabled: true, SigningKey: priv, CheckpointEvery: 2},
+	})
+	for i := 0; i < 5; i++ {
+		logger.Info().Int("i", i).Msg("signed")
+	}
+	logger.Close()
+
+	logStr := readLogFile(t, logPath)
+	if n := strings.Count(logStr, `{"logchain":"checkpoint"`); n != 3 {
+		t.Errorf("Expected checkpoints after seq 2, 4 and on Close, got %d:\n%s", n, logStr)
+	}
+
+	v, err := verifyChain(t, pub, logPath)
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if v.LastSigned() != 5 {
+		t.Errorf("Expected every entry to be signed, last signed seq %d", v.LastSigned())
+	}
+
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	_, err = verifyChain(t, otherPub, logPath)
+	expectBreak(t, err, "invalid checkpoint signature")
+
+	// Rewriting an entry and recomputing every later hash keeps the bare
+	// chain intact but no longer matches the signed checkpoint
+	var rewritten []string
+	prev := make([]byte, 32)
+	for _, line := range strings.Split(strings.TrimSpace(logStr), "\n") {
+		if body, _, _, ok := parseChainEntry([]byte(line)); ok {
+			body = bytes.Replace(body, []byte(`"i":0`), []byte(`"i":9`), 1)
+			prev = chainHash(prev, body)
+			line = string(body[:len(body)-1]) + chainHashPrefix + hex.EncodeToString(prev) + `"}`
+		}
+		rewritten = append(rewritten, line)
+	}
+	err = (&ChainVerifier{PublicKey: pub}).Verify("chain.log", strings.NewReader(strings.Join(rewritten, "\n")+"\n"))
+	expectBreak(t, err, "checkpoint does not match the chain")
+}
+
+func TestChainBody(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{`{"level":"info"}`, `{"level":"info","seq":7}`},
+		{`{}`, `{"seq":7}`},
+		{`plain text`, `{"raw":"plain text","seq":7}`},
+		{`{"logchain":"header"}`, `{"raw":"{\"logchain\":\"header\"}","seq":7}`},
+	}
+	for _, tt := range tests {
+		if got := string(chainBody([]byte(tt.line), 7)); got != tt.want {
+			t.Errorf("chainBody(%s) = %s, want %s", tt.line, got, tt.want)
+		}
+	}
+}
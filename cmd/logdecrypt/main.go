@@ -0,0 +1,177 @@
+// Command logdecrypt decrypts log files written with Config.Encryption and
+// can follow the active file across rotations, like tail -F.
+//
+// Usage:
+//
+//	logdecrypt -keys keys.txt logs/app-*.log logs/app.log
+//	logdecrypt -keys keys.txt -f logs/app.log
+//
+// The key file holds one "<key id>=<hex key>" pair per line; blank lines and
+// lines starting with "#" are ignored. A file that ends before its final
+// chunk, such as the active file of a running process, is reported on stderr
+// after its intact entries. A chunk that fails authentication stops the
+// output with exit status 1.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	logger "github.com/olegiv/go-logger"
+)
+
+func main() {
+	keysPath := flag.String("keys", "", "file with <key id>=<hex key> lines (required)")
+	follow := flag.Bool("f", false, "follow one file as it grows and is rotated")
+	poll := flag.Duration("poll", 500*time.Millisecond, "how often to check a followed file for changes")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, *keysPath, *follow, *poll, flag.Args(), os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "logdecrypt:", err)
+		os.Exit(1)
+	}
+}
+
+// run decrypts files in order, or follows the single file given
+func run(ctx context.Context, keysPath string, follow bool, poll time.Duration, files []string, out, errOut io.Writer) error {
+	if keysPath == "" {
+		return errors.New("-keys is required")
+	}
+	keys, err := logger.ReadKeyFile(keysPath)
+	if err != nil {
+		return err
+	}
+
+	if follow {
+		if len(files) != 1 {
+			return errors.New("-f takes exactly one file")
+		}
+		return followFile(ctx, keys, files[0], poll, out)
+	}
+	if len(files) == 0 {
+		return errors.New("no log files given")
+	}
+	for _, name := range files {
+		if err := decryptFile(keys, name, out, errOut); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decryptFile writes the plaintext of name to out. Truncation is reported
+// on errOut; any other error is returned.
+func decryptFile(keys map[string][]byte, name string, out, errOut io.Writer) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(out, logger.NewDecryptReader(f, keys))
+	if errors.Is(err, logger.ErrEncryptedTruncated) {
+		fmt.Fprintf(errOut, "logdecrypt: %s: %v\n", name, err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// followFile decrypts path as it grows. When the file is finished by a
+// rotation or Close, it waits for a new file at path and continues there.
+// It returns nil when ctx is done.
+func followFile(ctx context.Context, keys map[string][]byte, path string, poll time.Duration, out io.Writer) error {
+	for {
+		f, err := openWhenPresent(ctx, path, poll)
+		if err != nil {
+			return ignoreDone(ctx, err)
+		}
+		info, err := f.Stat()
+		if err == nil {
+			_, err = io.Copy(out, logger.NewDecryptReader(&followReader{ctx: ctx, f: f, poll: poll}, keys))
+		}
+		f.Close()
+		if err != nil {
+			return ignoreDone(ctx, fmt.Errorf("%s: %w", path, err))
+		}
+		if err := waitReplaced(ctx, path, info, poll); err != nil {
+			return ignoreDone(ctx, err)
+		}
+	}
+}
+
+// ignoreDone drops err once ctx is done, since stopping is not a failure
+func ignoreDone(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// followReader reads a growing file, waiting at end of file for more data
+type followReader struct {
+	ctx  context.Context
+	f    *os.File
+	poll time.Duration
+}
+
+// Read implements io.Reader
+func (r *followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.f.Read(p)
+		if n > 0 || !errors.Is(err, io.EOF) {
+			return n, err
+		}
+		if err := sleep(r.ctx, r.poll); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// openWhenPresent opens path, waiting for it to be created
+func openWhenPresent(ctx context.Context, path string, poll time.Duration) (*os.File, error) {
+	for {
+		f, err := os.Open(path)
+		if !errors.Is(err, os.ErrNotExist) {
+			return f, err
+		}
+		if err := sleep(ctx, poll); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// waitReplaced waits until path names a different file than info
+func waitReplaced(ctx context.Context, path string, info os.FileInfo, poll time.Duration) error {
+	for {
+		if cur, err := os.Stat(path); err == nil && !os.SameFile(cur, info) {
+			return nil
+		}
+		if err := sleep(ctx, poll); err != nil {
+			return err
+		}
+	}
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
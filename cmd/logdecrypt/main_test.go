@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	logger "github.com/olegiv/go-logger"
+)
+
+const testKeyHex = "0101010101010101010101010101010101010101010101010101010101010101"
+
+// syncBuffer is a bytes.Buffer safe for a concurrent writer and reader
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// setup returns an encrypted logger config in a new directory and the path
+// of a matching key file
+func setup(t *testing.T) (logger.Config, string) {
+	t.Helper()
+	dir := t.TempDir()
+	keys, err := logger.ParseKeys(strings.NewReader("k1=" + testKeyHex))
+	if err != nil {
+		t.Fatalf("ParseKeys returned error: %v", err)
+	}
+	keysPath := filepath.Join(dir, "keys.txt")
+	os.WriteFile(keysPath, []byte("# test key\nk1="+testKeyHex+"\n"), 0600)
+	return logger.Config{
+		LogDir:     filepath.Join(dir, "logs"),
+		Filename:   "app.log",
+		Encryption: logger.EncryptionConfig{KeyID: "k1", Keys: keys},
+	}, keysPath
+}
+
+// waitFor waits until out contains want
+func waitFor(t *testing.T, out *syncBuffer, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %q, got %q", want, out.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRunDecryptsFiles(t *testing.T) {
+	cfg, keysPath := setup(t)
+	l := logger.New(cfg)
+	l.Info().Msg("secret entry")
+	l.Close()
+
+	var out, errOut bytes.Buffer
+	err := run(context.Background(), keysPath, false, time.Second, []string{filepath.Join(cfg.LogDir, "app.log")}, &out, &errOut)
+	if err != nil {
+		t.Fatalf("run returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), `"message":"secret entry"`) || errOut.Len() != 0 {
+		t.Errorf("Unexpected output %q, stderr %q", out.String(), errOut.String())
+	}
+}
+
+func TestRunReportsTruncationAndTampering(t *testing.T) {
+	cfg, keysPath := setup(t)
+	logPath := filepath.Join(cfg.LogDir, "app.log")
+	l := logger.New(cfg)
+	l.Info().Msg("kept entry")
+	l.Info().Msg("cut entry")
+	l.Close()
+	data, _ := os.ReadFile(logPath)
+
+	os.WriteFile(logPath, data[:len(data)-30], 0600)
+	var out, errOut bytes.Buffer
+	if err := run(context.Background(), keysPath, false, time.Second, []string{logPath}, &out, &errOut); err != nil {
+		t.Fatalf("Expected truncation to be a warning, got %v", err)
+	}
+	if !strings.Contains(out.String(), "kept entry") || !strings.Contains(errOut.String(), "ends before its final chunk") {
+		t.Errorf("Unexpected output %q, stderr %q", out.String(), errOut.String())
+	}
+
+	data[len(data)-40] ^= 1
+	os.WriteFile(logPath, data, 0600)
+	out.Reset()
+	err := run(context.Background(), keysPath, false, time.Second, []string{logPath}, &out, &errOut)
+	if err == nil || !strings.Contains(err.Error(), "failed authentication") {
+		t.Errorf("Expected authentication error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "kept entry") {
+		t.Errorf("Expected entries before the modified chunk, got %q", out.String())
+	}
+}
+
+func TestFollowAcrossRotation(t *testing.T) {
+	cfg, keysPath := setup(t)
+	keys, _ := logger.ReadKeyFile(keysPath)
+	logPath := filepath.Join(cfg.LogDir, "app.log")
+
+	l := logger.New(cfg)
+	l.Info().Msg("before rotation")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() { done <- followFile(ctx, keys, logPath, 5*time.Millisecond, out) }()
+
+	waitFor(t, out, "before rotation")
+	l.Info().Msg("appended later")
+	waitFor(t, out, "appended later")
+
+	// A restart finishes the old file and rotates it away
+	l.Close()
+	time.Sleep(2 * time.Millisecond)
+	l = logger.New(cfg)
+	l.Info().Msg("after rotation")
+	waitFor(t, out, "after rotation")
+	l.Close()
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("followFile returned error: %v", err)
+	}
+}
+
+func TestRunRequiresKeys(t *testing.T) {
+	var out bytes.Buffer
+	if err := run(context.Background(), "", false, time.Second, []string{"x"}, &out, &out); err == nil {
+		t.Error("Expected error without -keys")
+	}
+	_, keysPath := setup(t)
+	if err := run(context.Background(), keysPath, true, time.Second, []string{"a", "b"}, &out, &out); err == nil {
+		t.Error("Expected error when following more than one file")
+	}
+}
@@ -0,0 +1,134 @@
+// Command logreident re-identifies pseudonymized values in log files for
+// authorized investigations. HMAC pseudonyms cannot be inverted, so the tool
+// recomputes tokens for a list of candidate identifiers and reports matches.
+//
+// Usage:
+//
+//	logreident -keys keys.txt -candidates users.txt [log files...]
+//	logreident -keys keys.txt -value alice
+//
+// The key file holds one "<key id>=<hex key>" pair per line; blank lines and
+// lines starting with "#" are ignored. Log files default to stdin.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	logger "github.com/olegiv/go-logger"
+)
+
+func main() {
+	keysPath := flag.String("keys", "", "file with <key id>=<hex key> lines (required)")
+	candidatesPath := flag.String("candidates", "", "file with one candidate identifier per line")
+	value := flag.String("value", "", "print the token of this value under every key instead of scanning logs")
+	flag.Parse()
+
+	if err := run(*keysPath, *candidatesPath, *value, flag.Args(), os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "logreident:", err)
+		os.Exit(1)
+	}
+}
+
+// run loads the keys and either prints tokens for value or scans the log files
+func run(keysPath, candidatesPath, value string, files []string, out io.Writer) error {
+	if keysPath == "" {
+		return errors.New("-keys is required")
+	}
+	keys, err := logger.ReadKeyFile(keysPath)
+	if err != nil {
+		return err
+	}
+
+	if value != "" {
+		for _, id := range sortedKeyIDs(keys) {
+			p, err := logger.NewPseudonymizer(id, keys)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(out, p.Token(value))
+		}
+		return nil
+	}
+
+	if candidatesPath == "" {
+		return errors.New("-candidates is required when scanning logs")
+	}
+	candidates, err := readLines(candidatesPath)
+	if err != nil {
+		return err
+	}
+	p, err := logger.NewPseudonymizer(sortedKeyIDs(keys)[0], keys)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		return scan(p, os.Stdin, candidates, out)
+	}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = scan(p, f, candidates, out)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scan prints "<token>\t<identifier>" for each new token found in r. Tokens
+// with no matching candidate are reported as "<unknown>".
+func scan(p *logger.Pseudonymizer, r io.Reader, candidates []string, out io.Writer) error {
+	seen := make(map[string]bool)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		for _, token := range p.FindPseudonyms(sc.Text()) {
+			if seen[token] {
+				continue
+			}
+			seen[token] = true
+			id, ok := p.Reidentify(token, candidates)
+			if !ok {
+				id = "<unknown>"
+			}
+			fmt.Fprintf(out, "%s\t%s\n", token, id)
+		}
+	}
+	return sc.Err()
+}
+
+// readLines returns the non-empty lines of the file at path
+func readLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// sortedKeyIDs returns the key IDs in a stable order
+func sortedKeyIDs(keys map[string][]byte) []string {
+	ids := make([]string, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
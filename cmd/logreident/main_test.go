@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	logger "github.com/olegiv/go-logger"
+)
+
+const testKeyHex = "30313233343536373839616263646566"
+
+func TestRunReidentifiesTokens(t *testing.T) {
+	dir := t.TempDir()
+	keysPath := filepath.Join(dir, "keys.txt")
+	candidatesPath := filepath.Join(dir, "users.txt")
+	logPath := filepath.Join(dir, "app.log")
+
+	keys, _ := logger.ParseKeys(strings.NewReader("v1=" + testKeyHex))
+	p, _ := logger.NewPseudonymizer("v1", keys)
+	alice, ghost := p.Token("alice"), p.Token("ghost")
+
+	os.WriteFile(keysPath, []byte("v1="+testKeyHex+"\n"), 0600)
+	os.WriteFile(candidatesPath, []byte("bob\nalice\n"), 0600)
+	os.WriteFile(logPath, []byte(`{"user":"`+alice+`"}`+"\n"+`{"user":"`+alice+`","peer":"`+ghost+`"}`+"\n"), 0600)
+
+	var out bytes.Buffer
+	if err := run(keysPath, candidatesPath, "", []string{logPath}, &out); err != nil {
+		t.Fatalf("run returned error: %v", err)
+	}
+	want := alice + "\talice\n" + ghost + "\t<unknown>\n"
+	if out.String() != want {
+		t.Errorf("Unexpected output:\n got: %q\nwant: %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := run(keysPath, "", "alice", nil, &out); err != nil {
+		t.Fatalf("run returned error: %v", err)
+	}
+	if out.String() != alice+"\n" {
+		t.Errorf("Expected token for -value, got %q", out.String())
+	}
+}
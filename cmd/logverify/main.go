@@ -0,0 +1,159 @@
+// Command logverify checks hash-chained log files written with
+// Config.HashChain enabled and reports the first break in the chain.
+//
+// Usage:
+//
+//	logverify [-pubkey key.hex] [-keys keys.txt] logs/app*.log
+//
+// Files are checked oldest first: rotated backups in timestamp order, then
+// files without a backup timestamp in the order given. With -pubkey, which
+// names a file holding the hex ed25519 public key, checkpoint signatures are
+// verified and entries after the last valid signature are reported.
+//
+// Files written with Config.Encryption are decrypted with -keys, a key file
+// holding one "<key id>=<hex key>" pair per line. The newest file may end
+// before its final chunk, as the active file of a running process does;
+// any other truncated or corrupt file is a break.
+//
+// The exit status is 0 when the chain is intact, 1 at the first break and 2
+// on usage or read errors.
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	logger "github.com/olegiv/go-logger"
+)
+
+// backupStamp matches the timestamp rotation appends to backup names
+var backupStamp = regexp.MustCompile(`-(\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}\.\d{3})(\.[^.]*)?$`)
+
+func main() {
+	pubPath := flag.String("pubkey", "", "file with the hex ed25519 public key for checkpoint signatures")
+	keysPath := flag.String("keys", "", "file with <key id>=<hex key> lines to decrypt encrypted log files")
+	flag.Parse()
+
+	broken, err := run(*pubPath, *keysPath, flag.Args(), os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "logverify:", err)
+		os.Exit(2)
+	}
+	if broken {
+		os.Exit(1)
+	}
+}
+
+// run verifies files and reports the outcome, returning true at a break
+func run(pubPath, keysPath string, files []string, out io.Writer) (bool, error) {
+	if len(files) == 0 {
+		return false, errors.New("no log files given")
+	}
+	v := &logger.ChainVerifier{}
+	if pubPath != "" {
+		pub, err := readPublicKey(pubPath)
+		if err != nil {
+			return false, err
+		}
+		v.PublicKey = pub
+	}
+	var keys map[string][]byte
+	if keysPath != "" {
+		var err error
+		if keys, err = logger.ReadKeyFile(keysPath); err != nil {
+			return false, fmt.Errorf("%s: %w", keysPath, err)
+		}
+	}
+
+	ordered := chainOrder(files)
+	var active string
+	for i, name := range ordered {
+		f, err := os.Open(name)
+		if err != nil {
+			return false, err
+		}
+		var r io.Reader = f
+		if keys != nil {
+			r = logger.NewDecryptReader(f, keys)
+		}
+		err = v.Verify(name, r)
+		f.Close()
+		if errors.Is(err, logger.ErrEncryptedTruncated) && i == len(ordered)-1 {
+			active, err = name, nil
+		}
+		var b *logger.ChainBreak
+		if errors.As(err, &b) {
+			fmt.Fprintf(out, "BROKEN %s\n", b)
+			return true, nil
+		}
+		if errors.Is(err, logger.ErrEncryptedTruncated) || errors.Is(err, logger.ErrEncryptedCorrupt) {
+			fmt.Fprintf(out, "BROKEN %s: %v\n", name, err)
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	fmt.Fprintf(out, "OK seq %d-%d\n", v.FirstSeq(), v.LastSeq())
+	if v.FirstSeq() > 1 {
+		fmt.Fprintf(out, "note: chain starts at seq %d, older files are missing or were removed by rotation\n", v.FirstSeq())
+	}
+	if v.PublicKey != nil && v.LastSigned() < v.LastSeq() {
+		fmt.Fprintf(out, "warning: entries after seq %d are not covered by a signed checkpoint\n", v.LastSigned())
+	}
+	if active != "" {
+		fmt.Fprintf(out, "note: %s ends before its final chunk, as the file of a running process does\n", active)
+	}
+	return false, nil
+}
+
+// chainOrder sorts backups by their rotation timestamp, oldest first, and
+// puts files without a timestamp after them in their original order
+func chainOrder(files []string) []string {
+	stamp := func(name string) string {
+		if m := backupStamp.FindStringSubmatch(filepath.Base(name)); m != nil {
+			return m[1]
+		}
+		return ""
+	}
+	sorted := slices.Clone(files)
+	slices.SortStableFunc(sorted, func(a, b string) int {
+		sa, sb := stamp(a), stamp(b)
+		switch {
+		case sa == "" && sb == "":
+			return 0
+		case sa == "":
+			return 1
+		case sb == "":
+			return -1
+		}
+		return strings.Compare(sa, sb)
+	})
+	return sorted
+}
+
+// readPublicKey loads a hex ed25519 public key from path
+func readPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("public key: %w", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key: expected %d bytes, got %d", ed25519.PublicKeySize, len(key))
+	}
+	return ed25519.PublicKey(key), nil
+}
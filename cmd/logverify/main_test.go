@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	logger "github.com/olegiv/go-logger"
+)
+
+// writeChain logs n entries into dir/app.log with checkpoints signed by priv
+func writeChain(t *testing.T, dir string, priv ed25519.PrivateKey, n int) string {
+	t.Helper()
+	l := logger.New(logger.Config{
+		LogDir:    dir,
+		Filename:  "app.log",
+		HashChain: logger.HashChainConfig{Enabled: true, SigningKey: priv},
+	})
+	for i := 0; i < n; i++ {
+		l.Info().Int("i", i).Msg("entry")
+	}
+	l.Close()
+	return filepath.Join(dir, "app.log")
+}
+
+func TestRunReportsIntactChain(t *testing.T) {
+	dir := t.TempDir()
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	logPath := writeChain(t, dir, priv, 3)
+	pubPath := filepath.Join(dir, "key.hex")
+	os.WriteFile(pubPath, []byte(hex.EncodeToString(pub)+"\n"), 0600)
+
+	var out bytes.Buffer
+	broken, err := run(pubPath, "", []string{logPath}, &out)
+	if err != nil || broken {
+		t.Fatalf("Expected intact chain, got broken=%v err=%v: %s", broken, err, out.String())
+	}
+	if out.String() != "OK seq 1-3\n" {
+		t.Errorf("Unexpected output: %q", out.String())
+	}
+}
+
+func TestRunReportsFirstBreak(t *testing.T) {
+	dir := t.TempDir()
+	logPath := writeChain(t, dir, nil, 3)
+
+	data, _ := os.ReadFile(logPath)
+	os.WriteFile(logPath, bytes.Replace(data, []byte(`"i":1`), []byte(`"i":7`), 1), 0600)
+
+	var out bytes.Buffer
+	broken, err := run("", "", []string{logPath}, &out)
+	if err != nil || !broken {
+		t.Fatalf("Expected a break, got broken=%v err=%v", broken, err)
+	}
+	if !strings.HasPrefix(out.String(), "BROKEN "+logPath+":3: chain broken at seq 2: hash mismatch") {
+		t.Errorf("Unexpected output: %q", out.String())
+	}
+}
+
+func TestRunWarnsAboutUnsignedTail(t *testing.T) {
+	dir := t.TempDir()
+	pub, _, _ := ed25519.GenerateKey(nil)
+	logPath := writeChain(t, dir, nil, 2)
+	pubPath := filepath.Join(dir, "key.hex")
+	os.WriteFile(pubPath, []byte(hex.EncodeToString(pub)), 0600)
+
+	var out bytes.Buffer
+	if _, err := run(pubPath, "", []string{logPath}, &out); err != nil {
+		t.Fatalf("run returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "warning: entries after seq 0 are not covered") {
+		t.Errorf("Expected unsigned tail warning, got %q", out.String())
+	}
+}
+
+func TestRunDecryptsEncryptedChain(t *testing.T) {
+	dir := t.TempDir()
+	const keyHex = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	keysPath := filepath.Join(dir, "keys.txt")
+	os.WriteFile(keysPath, []byte("k1="+keyHex+"\n"), 0600)
+	keys, _ := logger.ReadKeyFile(keysPath)
+
+	l := logger.New(logger.Config{
+		LogDir:     dir,
+		Filename:   "app.log.enc",
+		HashChain:  logger.HashChainConfig{Enabled: true},
+		Encryption: logger.EncryptionConfig{KeyID: "k1", Keys: keys},
+	})
+	l.Info().Msg("one")
+	l.Info().Msg("two")
+	logPath := filepath.Join(dir, "app.log.enc")
+
+	// Still open: the file has no final chunk yet
+	var out bytes.Buffer
+	broken, err := run("", keysPath, []string{logPath}, &out)
+	if err != nil || broken {
+		t.Fatalf("Expected intact chain, got broken=%v err=%v: %s", broken, err, out.String())
+	}
+	if !strings.HasPrefix(out.String(), "OK seq 1-2\n") || !strings.Contains(out.String(), "ends before its final chunk") {
+		t.Errorf("Unexpected output: %q", out.String())
+	}
+
+	l.Close()
+	out.Reset()
+	if broken, err := run("", keysPath, []string{logPath}, &out); err != nil || broken || out.String() != "OK seq 1-2\n" {
+		t.Errorf("Expected intact chain after Close, got broken=%v err=%v: %q", broken, err, out.String())
+	}
+
+	data, _ := os.ReadFile(logPath)
+	data[len(data)-30] ^= 1 // Inside the last entry's chunk
+	os.WriteFile(logPath, data, 0600)
+	out.Reset()
+	if broken, err := run("", keysPath, []string{logPath}, &out); err != nil || !broken || !strings.HasPrefix(out.String(), "BROKEN "+logPath) {
+		t.Errorf("Expected a break for a modified chunk, got broken=%v err=%v: %q", broken, err, out.String())
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	files := []string{
+		"logs/app.log",
+		"logs/app-2026-03-01T10-00-00.000.log",
+		"logs/app-2026-01-01T10-00-00.000.log",
+	}
+	want := []string{
+		"logs/app-2026-01-01T10-00-00.000.log",
+		"logs/app-2026-03-01T10-00-00.000.log",
+		"logs/app.log",
+	}
+	if got := chainOrder(files); !slices.Equal(got, want) {
+		t.Errorf("chainOrder = %v, want %v", got, want)
+	}
+}
+
+func TestReadPublicKeyRejectsBadKeys(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"short": "abcd", "nothex": "zz"} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0600)
+		if _, err := readPublicKey(path); err == nil {
+			t.Errorf("Expected error for %s key", name)
+		}
+	}
+}
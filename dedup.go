@@ -0,0 +1,195 @@
+package logger
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Field names added to the summary entry of suppressed duplicates
+const (
+	DedupCountFieldName = "repeated"
+	DedupFirstFieldName = "first_seen"
+	DedupLastFieldName  = "last_seen"
+)
+
+// defaultDedupMaxKeys is the number of distinct entries tracked by default
+const defaultDedupMaxKeys = 1000
+
+// DedupConfig suppresses identical entries. Entries are identical when their
+// level, message and the listed Fields match. The first occurrence is written
+// as usual; further occurrences within Window are dropped and reported by a
+// single summary entry once the window ends.
+type DedupConfig struct {
+	Window  time.Duration // How long after the first occurrence duplicates are suppressed; 0 disables Dedup
+	Fields  []string      // Fields compared besides level and message (default: none)
+	MaxKeys int           // Distinct entries tracked at once, least recently seen evicted first (default: 1000)
+}
+
+// dedupWriter drops duplicate entries and writes their summaries to next
+type dedupWriter struct {
+	next   io.Writer
+	window time.Duration
+	fields []string
+	max    int
+	stats  *loggerStats
+
+	mu    sync.Mutex
+	keys  map[string]*list.Element
+	order *list.List // *dedupEntry, most recently seen first
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// dedupEntry tracks one distinct entry during its window
+type dedupEntry struct {
+	key   string
+	entry jsonObject  // The first occurrence
+	start time.Time   // When the first occurrence was written
+	count int         // Duplicates suppressed so far
+	last  interface{} // Time field of the last duplicate
+}
+
+// newDedupWriter wraps next and starts the loop that ends windows
+func newDedupWriter(next io.Writer, cfg DedupConfig, stats *loggerStats) *dedupWriter {
+	w := &dedupWriter{
+		next:   next,
+		window: cfg.Window,
+		fields: cfg.Fields,
+		max:    cfg.MaxKeys,
+		stats:  stats,
+		keys:   make(map[string]*list.Element),
+		order:  list.New(),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if w.max <= 0 {
+		w.max = defaultDedupMaxKeys
+	}
+	go w.loop()
+	return w
+}
+
+// Write implements io.Writer. Lines that are not JSON objects are never
+// deduplicated.
+func (w *dedupWriter) Write(p []byte) (int, error) {
+	e, err := decodeEntry(p)
+	if err != nil {
+		return w.next.Write(p)
+	}
+	key := w.key(e)
+	now := time.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var errs []error
+	if el, ok := w.keys[key]; ok {
+		d := el.Value.(*dedupEntry)
+		if now.Sub(d.start) < w.window {
+			d.count++
+			d.last, _ = e.get(zerolog.TimestampFieldName)
+			w.order.MoveToFront(el)
+			w.stats.deduplicated.Add(1)
+			return len(p), nil
+		}
+		errs = append(errs, w.removeLocked(el))
+	}
+	if w.order.Len() >= w.max {
+		errs = append(errs, w.removeLocked(w.order.Back()))
+	}
+	w.keys[key] = w.order.PushFront(&dedupEntry{key: key, entry: e, start: now})
+
+	n, err := w.next.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, errors.Join(errs...)
+}
+
+// Close stops the loop and writes the summaries of all pending duplicates
+func (w *dedupWriter) Close() error {
+	if w.stop != nil {
+		close(w.stop)
+		<-w.done
+		w.stop = nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var errs []error
+	for el := w.order.Back(); el != nil; el = w.order.Back() {
+		errs = append(errs, w.removeLocked(el))
+	}
+	return errors.Join(errs...)
+}
+
+// loop ends expired windows, so summaries are written even when the
+// duplicates stop
+func (w *dedupWriter) loop() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case now := <-ticker.C:
+			w.mu.Lock()
+			for el := w.order.Back(); el != nil; {
+				prev := el.Prev()
+				if now.Sub(el.Value.(*dedupEntry).start) >= w.window {
+					_ = w.removeLocked(el)
+				}
+				el = prev
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// removeLocked stops tracking el and writes its summary if duplicates were
+// suppressed. It must be called with w.mu held.
+func (w *dedupWriter) removeLocked(el *list.Element) error {
+	d := w.order.Remove(el).(*dedupEntry)
+	delete(w.keys, d.key)
+	if d.count == 0 {
+		return nil
+	}
+	_, err := w.next.Write(d.summary())
+	return err
+}
+
+// summary returns the first occurrence stamped with the time of the last
+// duplicate, the number of duplicates and the first and last times
+func (d *dedupEntry) summary() []byte {
+	s := slices.Clone(d.entry)
+	first, _ := s.get(zerolog.TimestampFieldName)
+	if d.last != nil {
+		s.set(zerolog.TimestampFieldName, d.last)
+	}
+	s.set(DedupCountFieldName, json.Number(strconv.Itoa(d.count)))
+	s.set(DedupFirstFieldName, first)
+	s.set(DedupLastFieldName, d.last)
+	return s.encode(nil)
+}
+
+// key identifies an entry by level, message and the configured fields
+func (w *dedupWriter) key(e jsonObject) string {
+	buf := appendJSONString(nil, e.getString(zerolog.LevelFieldName))
+	buf = appendJSONString(buf, e.getString(zerolog.MessageFieldName))
+	for _, name := range w.fields {
+		buf = append(buf, ',')
+		if v, ok := e.get(name); ok {
+			buf = appendJSONValue(buf, v)
+		}
+	}
+	return string(buf)
+}
@@ -0,0 +1,142 @@
+package logger
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer is a bytes.Buffer safe for the dedup loop and the test
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// dedupLines returns the non-empty lines written so far
+func dedupLines(b *lockedBuffer) []string {
+	return strings.FieldsFunc(b.String(), func(r rune) bool { return r == '\n' })
+}
+
+func TestDedupSummaryOnClose(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := New(Config{LogDir: tmpDir, Filename: "dedup.log", Dedup: DedupConfig{Window: time.Hour}})
+	for range 1000 {
+		logger.Error().Str("dep", "db").Msg("connection refused")
+	}
+	logger.Info().Msg("other")
+	st := logger.Stats()
+	logger.Close()
+
+	lines := strings.Split(strings.TrimSpace(readLogFile(t, filepath.Join(tmpDir, "dedup.log"))), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected first occurrence, other entry and summary, got %d lines: %v", len(lines), lines)
+	}
+	if strings.Contains(lines[0], DedupCountFieldName) || !strings.Contains(lines[1], `"message":"other"`) {
+		t.Errorf("Expected the first occurrence to be written as is: %v", lines)
+	}
+	summary := lines[2]
+	for _, want := range []string{`"level":"error"`, `"dep":"db"`, `"message":"connection refused"`, `"repeated":999`, `"first_seen":"`, `"last_seen":"`} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Expected %s in summary: %s", want, summary)
+		}
+	}
+	if st.Deduplicated != 999 {
+		t.Errorf("Expected 999 suppressed entries, got %d", st.Deduplicated)
+	}
+}
+
+func TestDedupKeyFields(t *testing.T) {
+	out := &lockedBuffer{}
+	w := newDedupWriter(out, DedupConfig{Window: time.Hour, Fields: []string{"dep"}}, &loggerStats{})
+	for _, line := range []string{
+		`{"level":"error","dep":"db","attempt":1,"message":"down"}`,
+		`{"level":"error","dep":"db","attempt":2,"message":"down"}`,
+		`{"level":"error","dep":"cache","attempt":1,"message":"down"}`,
+		`{"level":"error","message":"down"}`,
+		`{"level":"warn","dep":"db","message":"down"}`,
+		`{"level":"error","dep":"db","message":"up"}`,
+	} {
+		w.Write([]byte(line + "\n"))
+	}
+	if got := len(dedupLines(out)); got != 5 {
+		t.Errorf("Expected only the second entry to be suppressed, got %d lines: %s", got, out)
+	}
+	w.Close()
+	if lines := dedupLines(out); !strings.Contains(lines[len(lines)-1], `"attempt":1,"message":"down","repeated":1`) {
+		t.Errorf("Expected the summary to keep the first occurrence's fields: %s", out)
+	}
+}
+
+func TestDedupWindowExpiry(t *testing.T) {
+	out := &lockedBuffer{}
+	w := newDedupWriter(out, DedupConfig{Window: 20 * time.Millisecond}, &loggerStats{})
+	defer w.Close()
+	line := []byte(`{"level":"error","time":"t","message":"flap"}` + "\n")
+	w.Write(line)
+	w.Write(line)
+	w.Write(line)
+
+	// The loop writes the summary once the window ends
+	deadline := time.Now().Add(2 * time.Second)
+	for len(dedupLines(out)) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	lines := dedupLines(out)
+	if len(lines) != 2 || !strings.Contains(lines[1], `"repeated":2`) {
+		t.Fatalf("Expected a summary after the window, got %v", lines)
+	}
+
+	// A later occurrence starts a new window
+	w.Write(line)
+	if lines := dedupLines(out); len(lines) != 3 || strings.Contains(lines[2], DedupCountFieldName) {
+		t.Errorf("Expected the entry to be written again after the window, got %v", lines)
+	}
+}
+
+func TestDedupBoundedKeys(t *testing.T) {
+	out := &lockedBuffer{}
+	w := newDedupWriter(out, DedupConfig{Window: time.Hour, MaxKeys: 2}, &loggerStats{})
+	write := func(msg string) { w.Write([]byte(`{"level":"info","message":"` + msg + `"}` + "\n")) }
+	write("a")
+	write("a")
+	write("b")
+	write("c") // evicts a, the least recently seen
+
+	if len(w.keys) != 2 || w.order.Len() != 2 {
+		t.Errorf("Expected 2 tracked keys, got %d", len(w.keys))
+	}
+	lines := dedupLines(out)
+	if len(lines) != 4 || !strings.Contains(lines[2], `"message":"a","repeated":1`) {
+		t.Errorf("Expected a's summary on eviction, got %v", lines)
+	}
+	w.Close()
+	if len(dedupLines(out)) != 4 {
+		t.Errorf("Expected no summaries for keys without duplicates, got %s", out)
+	}
+}
+
+func TestDedupPassesNonJSON(t *testing.T) {
+	out := &lockedBuffer{}
+	w := newDedupWriter(out, DedupConfig{Window: time.Hour}, &loggerStats{})
+	defer w.Close()
+	w.Write([]byte("plain\n"))
+	w.Write([]byte("plain\n"))
+	if out.String() != "plain\nplain\n" {
+		t.Errorf("Expected non-JSON lines to pass through, got %q", out)
+	}
+}
@@ -0,0 +1,298 @@
+package logger
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// encryptedMagic starts every encrypted log file
+	encryptedMagic = "GOLOGENC"
+
+	// encryptedVersion is the file format version following the magic
+	encryptedVersion = 1
+
+	// encryptionKeyLen is the AES-256 key length for key-encryption and data keys
+	encryptionKeyLen = 32
+
+	// noncePrefixLen is the random part of each chunk nonce; the rest is a
+	// 4-byte chunk counter and a final-chunk flag
+	noncePrefixLen = 7
+
+	// chunkOverhead is the length prefix plus the GCM tag added to each chunk
+	chunkOverhead = 4 + 16
+
+	// maxChunkLen bounds the ciphertext length accepted from a chunk header
+	maxChunkLen = 1 << 30
+)
+
+var (
+	// ErrEncryptionKey is returned when the active encryption key is missing or not 32 bytes
+	ErrEncryptionKey = errors.New("encryption key missing or not 32 bytes")
+
+	// ErrEncryptedTruncated is returned when an encrypted log ends before its
+	// final chunk: the file was cut off, the writer crashed, or it is still
+	// being written
+	ErrEncryptedTruncated = errors.New("encrypted log ends before its final chunk")
+
+	// ErrEncryptedCorrupt is returned when a header or chunk fails
+	// authentication, which happens if it was modified, removed or reordered
+	ErrEncryptedCorrupt = errors.New("encrypted log failed authentication")
+)
+
+// EncryptionConfig encrypts log files at rest with AES-256-GCM. Every file
+// gets a fresh random data key, wrapped with the key named KeyID and stored
+// in the file header with that ID, so keys change on every rotation. Entries
+// are sealed as separate chunks; a crash loses at most the chunk being
+// written, and reading stops at the first chunk that fails authentication.
+type EncryptionConfig struct {
+	KeyID string            // ID of the key that wraps new data keys
+	Keys  map[string][]byte // 32-byte keys by ID; keep retired keys to decrypt old files
+}
+
+// enabled reports whether encryption is configured
+func (c EncryptionConfig) enabled() bool {
+	return c.KeyID != "" || len(c.Keys) > 0
+}
+
+// encryptionKeys holds validated key-encryption keys
+type encryptionKeys struct {
+	id   string
+	keys map[string][]byte
+}
+
+// newEncryptionKeys validates c; every key must be 32 bytes
+func newEncryptionKeys(c EncryptionConfig) (*encryptionKeys, error) {
+	if len(c.Keys[c.KeyID]) != encryptionKeyLen {
+		return nil, ErrEncryptionKey
+	}
+	k := &encryptionKeys{id: c.KeyID, keys: make(map[string][]byte, len(c.Keys))}
+	for id, key := range c.Keys {
+		if len(key) != encryptionKeyLen {
+			return nil, fmt.Errorf("%w: %s", ErrEncryptionKey, id)
+		}
+		k.keys[id] = append([]byte(nil), key...)
+	}
+	return k, nil
+}
+
+// encryptedHeader is the JSON header of an encrypted file
+type encryptedHeader struct {
+	KeyID       string `json:"key_id"`
+	WrappedKey  string `json:"wrapped_key"`  // Data key sealed with the key-encryption key
+	NoncePrefix string `json:"nonce_prefix"` // Random prefix of every chunk nonce
+	Created     string `json:"created"`
+}
+
+// segment seals or opens the chunks of one encrypted file
+type segment struct {
+	aead    cipher.AEAD
+	prefix  []byte
+	aad     []byte // The header, so chunks are bound to it
+	counter uint32
+}
+
+// newSegment creates a data key for a new file and returns the segment with
+// the file header to write before any chunk
+func newSegment(k *encryptionKeys) (*segment, []byte, error) {
+	dataKey := make([]byte, encryptionKeyLen)
+	prefix := make([]byte, noncePrefixLen)
+	wrapNonce := make([]byte, 12)
+	for _, b := range [][]byte{dataKey, prefix, wrapNonce} {
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	kek, err := newGCM(k.keys[k.id])
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapped := kek.Seal(wrapNonce, wrapNonce, dataKey, []byte(k.id))
+	hdr, err := json.Marshal(encryptedHeader{
+		KeyID:       k.id,
+		WrappedKey:  base64.StdEncoding.EncodeToString(wrapped),
+		NoncePrefix: base64.StdEncoding.EncodeToString(prefix),
+		Created:     time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := make([]byte, 0, len(encryptedMagic)+3+len(hdr))
+	out = append(out, encryptedMagic...)
+	out = append(out, encryptedVersion)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(hdr)))
+	out = append(out, hdr...)
+	return &segment{aead: aead, prefix: prefix, aad: hdr}, out, nil
+}
+
+// newGCM returns AES-GCM for key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// nonce returns the nonce of the current chunk
+func (s *segment) nonce(last bool) []byte {
+	n := make([]byte, 0, 12)
+	n = append(n, s.prefix...)
+	n = binary.BigEndian.AppendUint32(n, s.counter)
+	if last {
+		return append(n, 1)
+	}
+	return append(n, 0)
+}
+
+// seal encrypts p as the next chunk. The final chunk is flagged in its
+// nonce, so a file cut at a chunk boundary is still detected.
+func (s *segment) seal(p []byte, last bool) ([]byte, error) {
+	if s.counter == ^uint32(0) {
+		return nil, errors.New("encrypted log chunk counter exhausted")
+	}
+	out := make([]byte, 4, 4+len(p)+s.aead.Overhead())
+	out = s.aead.Seal(out, s.nonce(last), p, s.aad)
+	binary.BigEndian.PutUint32(out, uint32(len(out)-4))
+	s.counter++
+	return out, nil
+}
+
+// DecryptReader decrypts an encrypted log file as a stream. Read returns
+// io.EOF after the final chunk, ErrEncryptedTruncated if the input ends
+// before it, and an error wrapping ErrEncryptedCorrupt at the first chunk
+// that fails authentication. Plaintext from earlier chunks is returned
+// first. Nothing after the final chunk is read.
+type DecryptReader struct {
+	r     io.Reader
+	keys  map[string][]byte
+	keyID string
+	seg   *segment
+	buf   []byte
+	err   error
+}
+
+// NewDecryptReader returns a reader decrypting r with keys, which map key
+// IDs to 32-byte keys
+func NewDecryptReader(r io.Reader, keys map[string][]byte) *DecryptReader {
+	return &DecryptReader{r: r, keys: keys}
+}
+
+// KeyID returns the ID of the key named in the file header, once read
+func (d *DecryptReader) KeyID() string {
+	return d.keyID
+}
+
+// Read implements io.Reader
+func (d *DecryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.buf, d.err = d.next()
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// next returns the plaintext of the next chunk
+func (d *DecryptReader) next() ([]byte, error) {
+	if d.seg == nil {
+		return nil, d.readHeader()
+	}
+
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(d.r, lenBuf[:]); err != nil {
+		return nil, truncated(err)
+	}
+	n := binary.BigEndian.Uint32(lenBuf[:])
+	if n < uint32(d.seg.aead.Overhead()) || n > maxChunkLen {
+		return nil, fmt.Errorf("%w: chunk %d has invalid length %d", ErrEncryptedCorrupt, d.seg.counter, n)
+	}
+	ct := make([]byte, n)
+	if _, err := io.ReadFull(d.r, ct); err != nil {
+		return nil, truncated(err)
+	}
+
+	pt, err := d.seg.aead.Open(nil, d.seg.nonce(false), ct, d.seg.aad)
+	if err == nil {
+		d.seg.counter++
+		return pt, nil
+	}
+	if pt, err = d.seg.aead.Open(nil, d.seg.nonce(true), ct, d.seg.aad); err == nil {
+		return pt, io.EOF
+	}
+	return nil, fmt.Errorf("%w: chunk %d", ErrEncryptedCorrupt, d.seg.counter)
+}
+
+// readHeader reads the file header and unwraps the data key
+func (d *DecryptReader) readHeader() error {
+	var pre [len(encryptedMagic) + 3]byte
+	if _, err := io.ReadFull(d.r, pre[:]); err != nil {
+		return truncated(err)
+	}
+	if string(pre[:len(encryptedMagic)]) != encryptedMagic {
+		return errors.New("not an encrypted log file")
+	}
+	if v := pre[len(encryptedMagic)]; v != encryptedVersion {
+		return fmt.Errorf("unsupported encrypted log version %d", v)
+	}
+	raw := make([]byte, binary.BigEndian.Uint16(pre[len(encryptedMagic)+1:]))
+	if _, err := io.ReadFull(d.r, raw); err != nil {
+		return truncated(err)
+	}
+
+	var hdr encryptedHeader
+	if err := json.Unmarshal(raw, &hdr); err != nil {
+		return fmt.Errorf("%w: malformed header", ErrEncryptedCorrupt)
+	}
+	d.keyID = hdr.KeyID
+	kek, ok := d.keys[hdr.KeyID]
+	if !ok {
+		return fmt.Errorf("unknown encryption key id %q", hdr.KeyID)
+	}
+	wrapped, err1 := base64.StdEncoding.DecodeString(hdr.WrappedKey)
+	prefix, err2 := base64.StdEncoding.DecodeString(hdr.NoncePrefix)
+	if err1 != nil || err2 != nil || len(wrapped) < 12 || len(prefix) != noncePrefixLen {
+		return fmt.Errorf("%w: malformed header", ErrEncryptedCorrupt)
+	}
+
+	kekGCM, err := newGCM(kek)
+	if err != nil {
+		return err
+	}
+	dataKey, err := kekGCM.Open(nil, wrapped[:12], wrapped[12:], []byte(hdr.KeyID))
+	if err != nil {
+		return fmt.Errorf("%w: data key does not unwrap with key %q", ErrEncryptedCorrupt, hdr.KeyID)
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return err
+	}
+	d.seg = &segment{aead: aead, prefix: prefix, aad: raw}
+	return nil
+}
+
+// truncated maps the end of input inside the file to ErrEncryptedTruncated
+func truncated(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrEncryptedTruncated
+	}
+	return err
+}
@@ -0,0 +1,273 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testEncKey1 = bytes.Repeat([]byte{1}, 32)
+	testEncKey2 = bytes.Repeat([]byte{2}, 32)
+)
+
+// encryptedLog writes msgs to an encrypted log in a new directory and
+// returns the raw file
+func encryptedLog(t *testing.T, msgs ...string) []byte {
+	t.Helper()
+	tmpDir := t.TempDir()
+	logger := New(Config{
+		LogDir:     tmpDir,
+		Filename:   "enc.log",
+		Encryption: EncryptionConfig{KeyID: "k1", Keys: map[string][]byte{"k1": testEncKey1}},
+	})
+	for _, msg := range msgs {
+		logger.Info().Msg(msg)
+	}
+	logger.Close()
+	data, err := os.ReadFile(filepath.Join(tmpDir, "enc.log"))
+	if err != nil {
+		t.Fatalf("ReadFile returned error: %v", err)
+	}
+	return data
+}
+
+// decrypt decrypts data with the test keys, returning the plaintext read
+// before any error
+func decrypt(data []byte) (string, error) {
+	d := NewDecryptReader(bytes.NewReader(data), map[string][]byte{"k1": testEncKey1, "k2": testEncKey2})
+	out, err := io.ReadAll(d)
+	return string(out), err
+}
+
+// splitChunks splits an encrypted file into its header and chunks
+func splitChunks(t *testing.T, data []byte) ([]byte, [][]byte) {
+	t.Helper()
+	hdrEnd := len(encryptedMagic) + 3 + int(binary.BigEndian.Uint16(data[len(encryptedMagic)+1:]))
+	var chunks [][]byte
+	for rest := data[hdrEnd:]; len(rest) > 0; {
+		n := 4 + int(binary.BigEndian.Uint32(rest))
+		chunks = append(chunks, rest[:n])
+		rest = rest[n:]
+	}
+	return data[:hdrEnd], chunks
+}
+
+func TestEncryptedLogRoundTrip(t *testing.T) {
+	data := encryptedLog(t, "regulated alpha", "regulated bravo")
+
+	if !bytes.HasPrefix(data, []byte(encryptedMagic)) {
+		t.Errorf("Expected file to start with %q", encryptedMagic)
+	}
+	if bytes.Contains(data, []byte("regulated")) {
+		t.Error("Expected no plaintext in the encrypted file")
+	}
+
+	d := NewDecryptReader(bytes.NewReader(data), map[string][]byte{"k1": testEncKey1})
+	out, err := io.ReadAll(d)
+	if err != nil {
+		t.Fatalf("Decrypt returned error: %v", err)
+	}
+	if d.KeyID() != "k1" {
+		t.Errorf("Expected key ID k1 from header, got %q", d.KeyID())
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"message":"regulated alpha"`) || !strings.Contains(lines[1], `"message":"regulated bravo"`) {
+		t.Errorf("Unexpected plaintext: %s", out)
+	}
+
+	// One chunk per entry plus the final chunk
+	if _, chunks := splitChunks(t, data); len(chunks) != 3 {
+		t.Errorf("Expected 3 chunks, got %d", len(chunks))
+	}
+}
+
+func TestEncryptedLogTruncation(t *testing.T) {
+	data := encryptedLog(t, "first", "second")
+	header, chunks := splitChunks(t, data)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantOut []string
+	}{
+		{"inside header", data[:len(header)-5], nil},
+		{"after header", header, nil},
+		{"inside first chunk", data[:len(header)+10], nil},
+		{"inside second chunk", data[:len(header)+len(chunks[0])+len(chunks[1])-1], []string{"first"}},
+		{"final chunk removed", data[:len(data)-len(chunks[2])], []string{"first", "second"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := decrypt(tt.data)
+			if !errors.Is(err, ErrEncryptedTruncated) {
+				t.Errorf("Expected ErrEncryptedTruncated, got %v", err)
+			}
+			if got := strings.Count(out, "\n"); got != len(tt.wantOut) {
+				t.Errorf("Expected %d intact entries, got %q", len(tt.wantOut), out)
+			}
+			for _, msg := range tt.wantOut {
+				if !strings.Contains(out, msg) {
+					t.Errorf("Expected %q to survive truncation, got %q", msg, out)
+				}
+			}
+		})
+	}
+}
+
+func TestEncryptedLogTampering(t *testing.T) {
+	data := encryptedLog(t, "first", "second", "third")
+	header, chunks := splitChunks(t, data)
+	join := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+
+	flipped := slices.Clone(data)
+	flipped[len(header)+len(chunks[0])+8] ^= 1
+
+	tests := []struct {
+		name      string
+		data      []byte
+		wantFirst bool // The first entry is still returned
+	}{
+		{"flipped bit", flipped, true},
+		{"removed chunk", join(header, chunks[0], chunks[2], chunks[3]), true},
+		{"reordered chunks", join(header, chunks[1], chunks[0], chunks[2], chunks[3]), false},
+		{"early final chunk", join(header, chunks[0], chunks[3][:4], chunks[1][4:]), true},
+		{"modified header", bytes.Replace(data, []byte(`"created":"`), []byte(`"created":"1`), 1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := decrypt(tt.data)
+			if !errors.Is(err, ErrEncryptedCorrupt) {
+				t.Errorf("Expected ErrEncryptedCorrupt, got %v", err)
+			}
+			if strings.Contains(out, "first") != tt.wantFirst {
+				t.Errorf("Unexpected plaintext before the corrupt chunk: %q", out)
+			}
+		})
+	}
+
+	wrongKey := NewDecryptReader(bytes.NewReader(data), map[string][]byte{"k1": testEncKey2})
+	if _, err := io.ReadAll(wrongKey); !errors.Is(err, ErrEncryptedCorrupt) {
+		t.Errorf("Expected ErrEncryptedCorrupt with the wrong key, got %v", err)
+	}
+	unknown := NewDecryptReader(bytes.NewReader(data), map[string][]byte{"k2": testEncKey2})
+	if _, err := io.ReadAll(unknown); err == nil || !strings.Contains(err.Error(), `unknown encryption key id "k1"`) {
+		t.Errorf("Expected unknown key error, got %v", err)
+	}
+}
+
+func TestEncryptedLogKeysRotate(t *testing.T) {
+	tmpDir := t.TempDir()
+	keys := map[string][]byte{"k1": testEncKey1, "k2": testEncKey2}
+
+	logger := New(Config{LogDir: tmpDir, Filename: "enc.log", Encryption: EncryptionConfig{KeyID: "k1", Keys: keys}})
+	logger.Info().Msg("one")
+	time.Sleep(2 * time.Millisecond)
+	if err := logger.fileWriter.(*rotatingWriter).Rotate(); err != nil {
+		t.Fatalf("Rotate returned error: %v", err)
+	}
+	logger.Info().Msg("two")
+	logger.Close()
+
+	// A restart cannot append to an encrypted file, so it starts a new one
+	// under the new key
+	time.Sleep(2 * time.Millisecond)
+	logger = New(Config{LogDir: tmpDir, Filename: "enc.log", Encryption: EncryptionConfig{KeyID: "k2", Keys: keys}})
+	logger.Info().Msg("three")
+	logger.Close()
+
+	files := chainFiles(t, tmpDir, "enc.log")
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 files, got %v", files)
+	}
+	wrapped := map[string]bool{}
+	for i, name := range files {
+		data, _ := os.ReadFile(name)
+		header, _ := splitChunks(t, data)
+		wrapped[string(header)] = true
+
+		d := NewDecryptReader(bytes.NewReader(data), keys)
+		out, err := io.ReadAll(d)
+		if err != nil {
+			t.Fatalf("Decrypting %s returned error: %v", name, err)
+		}
+		wantKey, wantMsg := "k1", []string{"one", "two", "three"}[i]
+		if i == 2 {
+			wantKey = "k2"
+		}
+		if d.KeyID() != wantKey || !strings.Contains(string(out), `"message":"`+wantMsg+`"`) {
+			t.Errorf("File %d: expected %s under %s, got %q under %s", i, wantMsg, wantKey, out, d.KeyID())
+		}
+	}
+	if len(wrapped) != 3 {
+		t.Error("Expected a different data key in every file")
+	}
+}
+
+func TestEncryptedLogCrashRecovery(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "enc.log")
+	cfg := Config{
+		LogDir:     tmpDir,
+		Filename:   "enc.log",
+		Encryption: EncryptionConfig{KeyID: "k1", Keys: map[string][]byte{"k1": testEncKey1}},
+		HashChain:  HashChainConfig{Enabled: true},
+	}
+
+	// Never closed, as after a crash
+	crashed := New(cfg)
+	crashed.Info().Msg("before crash")
+	data, _ := os.ReadFile(logPath)
+	out, err := decrypt(data)
+	if !errors.Is(err, ErrEncryptedTruncated) || !strings.Contains(out, "before crash") {
+		t.Errorf("Expected written entries and ErrEncryptedTruncated, got %q, %v", out, err)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	forgetSharedFiles()
+	logger := New(cfg)
+	logger.Info().Msg("after restart")
+	logger.Close()
+	// Release the crashed writer's handles without writing its final chunk
+	crashed.fileWriter.(*chainWriter).rw.file.Close()
+	crashed.fileWriter.(*chainWriter).rw.root.Close()
+
+	// The hash chain resumes from the decrypted tail of the crashed file
+	v := &ChainVerifier{}
+	for _, name := range chainFiles(t, tmpDir, "enc.log") {
+		data, _ := os.ReadFile(name)
+		plain, _ := decrypt(data)
+		if err := v.Verify(name, strings.NewReader(plain)); err != nil {
+			t.Fatalf("Verify returned error: %v", err)
+		}
+	}
+	if v.LastSeq() != 2 {
+		t.Errorf("Expected the chain to continue across the crash, last seq %d", v.LastSeq())
+	}
+}
+
+func TestEncryptionFailsClosed(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	for _, enc := range []EncryptionConfig{
+		{KeyID: "k1"},
+		{KeyID: "k1", Keys: map[string][]byte{"k1": []byte("short")}},
+		{KeyID: "k1", Keys: map[string][]byte{"k1": testEncKey1, "old": []byte("short")}},
+	} {
+		logger := New(Config{LogDir: tmpDir, Filename: "enc.log", Encryption: enc})
+		if logger.fileWriter != nil {
+			t.Errorf("Expected stderr fallback for %+v", enc)
+		}
+		logger.Close()
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "enc.log")); !os.IsNotExist(err) {
+		t.Error("Expected no log file to be created without a valid key")
+	}
+}
@@ -0,0 +1,250 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// jsonField is one key/value pair of a decoded JSON object
+type jsonField struct {
+	Key   string
+	Value interface{}
+}
+
+// jsonObject is a decoded JSON object that keeps the encoding order of its
+// fields, so rewritten entries look like the ones zerolog produced. Values
+// are jsonObject, []interface{}, string, json.Number, bool or nil.
+type jsonObject []jsonField
+
+// get returns the value of the first field named key
+func (o jsonObject) get(key string) (interface{}, bool) {
+	for _, f := range o {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// getString returns the string value of the first field named key
+func (o jsonObject) getString(key string) string {
+	v, _ := o.get(key)
+	s, _ := v.(string)
+	return s
+}
+
+// set replaces the value of key, or appends the field if it does not exist
+func (o *jsonObject) set(key string, value interface{}) {
+	for i := range *o {
+		if (*o)[i].Key == key {
+			(*o)[i].Value = value
+			return
+		}
+	}
+	*o = append(*o, jsonField{Key: key, Value: value})
+}
+
+// errNotObject is returned when a line is not a single JSON object
+var errNotObject = errors.New("not a JSON object")
+
+// decodeEntry decodes one JSON log line, preserving field order
+func decodeEntry(line []byte) (jsonObject, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return nil, errNotObject
+	}
+	obj, err := decodeObject(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errNotObject
+	}
+	return obj, nil
+}
+
+// decodeObject decodes the remainder of an object after its opening brace
+func decodeObject(dec *json.Decoder) (jsonObject, error) {
+	obj := jsonObject{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, errNotObject
+		}
+		value, err := decodeValue(dec)
+		if err != nil {
+			return nil, err
+		}
+		obj = append(obj, jsonField{Key: key, Value: value})
+	}
+	// Consume the closing brace
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// decodeValue decodes the next value, recursing into objects and arrays
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			return decodeObject(dec)
+		case '[':
+			arr := []interface{}{}
+			for dec.More() {
+				v, err := decodeValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, v)
+			}
+			// Consume the closing bracket
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return arr, nil
+		}
+		return nil, errNotObject
+	default:
+		return t, nil
+	}
+}
+
+// MarshalJSON implements json.Marshaler so objects built by the logger keep
+// their field order when encoded by zerolog
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	return appendJSONValue(nil, o), nil
+}
+
+// encode appends the compact JSON encoding of o followed by a newline
+func (o jsonObject) encode(buf []byte) []byte {
+	buf = appendJSONValue(buf, o)
+	return append(buf, '\n')
+}
+
+// appendJSONValue appends the JSON encoding of a decoded value
+func appendJSONValue(buf []byte, v interface{}) []byte {
+	switch val := v.(type) {
+	case nil:
+		return append(buf, "null"...)
+	case bool:
+		return strconv.AppendBool(buf, val)
+	case json.Number:
+		return append(buf, val...)
+	case string:
+		return appendJSONString(buf, val)
+	case jsonObject:
+		buf = append(buf, '{')
+		for i, f := range val {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendJSONString(buf, f.Key)
+			buf = append(buf, ':')
+			buf = appendJSONValue(buf, f.Value)
+		}
+		return append(buf, '}')
+	case []interface{}:
+		buf = append(buf, '[')
+		for i, item := range val {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendJSONValue(buf, item)
+		}
+		return append(buf, ']')
+	default:
+		// Values produced by processors outside the decoded set
+		b, err := json.Marshal(val)
+		if err != nil {
+			return appendJSONString(buf, err.Error())
+		}
+		return append(buf, b...)
+	}
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends s as a JSON string the way zerolog encodes it:
+// control characters are escaped and invalid UTF-8 becomes U+FFFD
+func appendJSONString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				buf = append(buf, '\\', c)
+			case c == '\n':
+				buf = append(buf, '\\', 'n')
+			case c == '\r':
+				buf = append(buf, '\\', 'r')
+			case c == '\t':
+				buf = append(buf, '\\', 't')
+			case c < 0x20:
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			default:
+				buf = append(buf, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, `\ufffd`...)
+		} else {
+			buf = append(buf, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(buf, '"')
+}
+
+// entryProcessor rewrites a decoded entry in place. Returning false drops
+// the entry.
+type entryProcessor func(e *jsonObject) bool
+
+// processingWriter decodes each entry once, runs it through a list of
+// processors and writes the re-encoded result to next. Lines that are not
+// JSON objects are passed through unchanged.
+type processingWriter struct {
+	next       io.Writer
+	processors []entryProcessor
+}
+
+// Write implements io.Writer
+func (w *processingWriter) Write(p []byte) (int, error) {
+	e, err := decodeEntry(p)
+	if err != nil {
+		return w.next.Write(p)
+	}
+	for _, process := range w.processors {
+		if !process(&e) {
+			return len(p), nil
+		}
+	}
+	if _, err := w.next.Write(e.encode(make([]byte, 0, len(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeEntryRoundTrip(t *testing.T) {
+	tests := []string{
+		`{"level":"info","time":"2026-01-02T03:04:05Z","message":"hello"}`,
+		`{"z":1,"a":2,"m":3}`,
+		`{"nested":{"b":[1,2.5,-3e10,{"deep":true}],"a":null},"empty":{},"list":[]}`,
+		`{"escaped":"quote \" backslash \\ newline \n tab \t ctrl \u0001"}`,
+		`{"unicode":"héllo 世界"}`,
+	}
+
+	for _, line := range tests {
+		t.Run(line, func(t *testing.T) {
+			e, err := decodeEntry([]byte(line + "\n"))
+			if err != nil {
+				t.Fatalf("decodeEntry returned error: %v", err)
+			}
+			got := string(e.encode(nil))
+			if got != line+"\n" {
+				t.Errorf("Round trip mismatch:\n got: %s\nwant: %s", got, line)
+			}
+		})
+	}
+}
+
+func TestDecodeEntryRejectsNonObjects(t *testing.T) {
+	for _, line := range []string{
+		"plain text\n",
+		`["array"]`,
+		`"string"`,
+		`{"a":1}{"b":2}`,
+		`{"a":`,
+	} {
+		if _, err := decodeEntry([]byte(line)); err == nil {
+			t.Errorf("Expected error decoding %q", line)
+		}
+	}
+}
+
+func TestAppendJSONStringInvalidUTF8(t *testing.T) {
+	got := string(appendJSONString(nil, "bad\xffbyte"))
+	if got != `"bad\ufffdbyte"` {
+		t.Errorf("Expected invalid UTF-8 to be replaced, got %s", got)
+	}
+}
+
+func TestJSONObjectSetAndGet(t *testing.T) {
+	e := jsonObject{{Key: "a", Value: "1"}}
+	e.set("a", "2")
+	e.set("b", "3")
+
+	if e.getString("a") != "2" || e.getString("b") != "3" {
+		t.Errorf("Unexpected values after set: %v", e)
+	}
+	if len(e) != 2 {
+		t.Errorf("Expected 2 fields, got %d", len(e))
+	}
+	if _, ok := e.get("missing"); ok {
+		t.Error("Expected missing key to be reported as absent")
+	}
+}
+
+func TestProcessingWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := &processingWriter{
+		next: &out,
+		processors: []entryProcessor{
+			func(e *jsonObject) bool { e.set("added", "yes"); return true },
+			func(e *jsonObject) bool { return e.getString("message") != "drop me" },
+		},
+	}
+
+	w.Write([]byte(`{"message":"keep me"}` + "\n"))
+	w.Write([]byte(`{"message":"drop me"}` + "\n"))
+	w.Write([]byte("not json\n"))
+
+	want := `{"message":"keep me","added":"yes"}` + "\n" + "not json\n"
+	if out.String() != want {
+		t.Errorf("Unexpected output:\n got: %q\nwant: %q", out.String(), want)
+	}
+}
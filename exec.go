@@ -0,0 +1,184 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// CmdOptions configures how a child process's output is logged
+type CmdOptions struct {
+	StdoutLevel     string // Level for stdout lines (default: "info")
+	StderrLevel     string // Level for stderr lines (default: "warn")
+	JSONPassthrough bool   // Log JSON object lines with their own level, message and fields
+}
+
+// CmdCapture streams the stdout and stderr of an exec.Cmd into the log line
+// by line and records an exit summary when the process finishes
+type CmdCapture struct {
+	cmd    *exec.Cmd
+	logger *Logger
+	opts   CmdOptions
+
+	stdout *LineWriter
+	stderr *LineWriter
+
+	once    sync.Once
+	procLog *Logger // Child logger with cmd and pid fields, built once the process starts
+	started time.Time
+}
+
+// CaptureCmd attaches to cmd so that its stdout and stderr are logged through
+// a child logger with cmd, pid and stream fields. It must be called before the
+// command is started; start and wait for the command through the returned
+// CmdCapture. Writers already set on cmd.Stdout or cmd.Stderr keep receiving
+// the raw output.
+func (l *Logger) CaptureCmd(cmd *exec.Cmd, opts CmdOptions) *CmdCapture {
+	if opts.StdoutLevel == "" {
+		opts.StdoutLevel = "info"
+	}
+	if opts.StderrLevel == "" {
+		opts.StderrLevel = "warn"
+	}
+
+	c := &CmdCapture{cmd: cmd, logger: l, opts: opts}
+	c.stdout = c.streamWriter("stdout", parseLogLevel(opts.StdoutLevel))
+	c.stderr = c.streamWriter("stderr", parseLogLevel(opts.StderrLevel))
+
+	cmd.Stdout = teeWriter(cmd.Stdout, c.stdout)
+	cmd.Stderr = teeWriter(cmd.Stderr, c.stderr)
+	return c
+}
+
+// Start starts the command
+func (c *CmdCapture) Start() error {
+	c.started = time.Now()
+	if err := c.cmd.Start(); err != nil {
+		c.logger.Error().
+			Err(err).
+			Str("cmd", c.name()).
+			Msg("Failed to start process")
+		return err
+	}
+	return nil
+}
+
+// Wait waits for the command to exit and its output to be logged, then logs
+// an exit summary with the exit code and duration
+func (c *CmdCapture) Wait() error {
+	err := c.cmd.Wait()
+
+	// Flush trailing lines without a newline
+	_ = c.stdout.Close()
+	_ = c.stderr.Close()
+
+	exitCode := -1
+	if c.cmd.ProcessState != nil {
+		exitCode = c.cmd.ProcessState.ExitCode()
+	}
+
+	event := c.processLogger().Info()
+	if err != nil || exitCode != 0 {
+		event = c.processLogger().Error().Err(err)
+	}
+	event.
+		Int("exit_code", exitCode).
+		Dur("duration", time.Since(c.started)).
+		Msg("Process exited")
+
+	return err
+}
+
+// Run starts the command and waits for it to complete
+func (c *CmdCapture) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// streamWriter returns a LineWriter that logs the lines of one stream
+func (c *CmdCapture) streamWriter(stream string, level zerolog.Level) *LineWriter {
+	w := &LineWriter{logger: c.logger, level: level}
+	w.emit = func(level zerolog.Level, line string) {
+		log := c.processLogger()
+		if c.opts.JSONPassthrough && c.logJSONLine(log, stream, level, line) {
+			return
+		}
+		log.WithLevel(level).Str("stream", stream).Msg(line)
+	}
+	return w
+}
+
+// processLogger returns the child logger carrying the cmd and pid fields.
+// Output is only copied after the process has started, so the pid is known
+// by the time the first line is logged.
+func (c *CmdCapture) processLogger() *Logger {
+	c.once.Do(func() {
+		fields := map[string]interface{}{"cmd": c.name()}
+		if c.cmd.Process != nil {
+			fields["pid"] = c.cmd.Process.Pid
+		}
+		c.procLog = c.logger.WithFields(fields)
+	})
+	return c.procLog
+}
+
+// name returns the program name without its directory
+func (c *CmdCapture) name() string {
+	return filepath.Base(c.cmd.Path)
+}
+
+// reservedCmdFields are set by CmdCapture and never taken from child JSON
+var reservedCmdFields = map[string]bool{
+	"cmd": true, "pid": true, "stream": true,
+	zerolog.LevelFieldName:     true,
+	zerolog.MessageFieldName:   true,
+	zerolog.TimestampFieldName: true,
+	"msg":                      true,
+}
+
+// logJSONLine logs a JSON object line emitted by the child with its own
+// level, message and fields. It reports false if line is not a JSON object.
+func (c *CmdCapture) logJSONLine(log *Logger, stream string, level zerolog.Level, line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") {
+		return false
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(trimmed), &fields); err != nil {
+		return false
+	}
+
+	if s, ok := fields[zerolog.LevelFieldName].(string); ok {
+		if parsed, ok := levelPrefixes[strings.ToUpper(s)]; ok {
+			level = parsed
+		}
+	}
+	message, _ := fields[zerolog.MessageFieldName].(string)
+	if message == "" {
+		message, _ = fields["msg"].(string)
+	}
+	for k := range fields {
+		if reservedCmdFields[k] {
+			delete(fields, k)
+		}
+	}
+
+	log.WithLevel(level).Str("stream", stream).Fields(fields).Msg(message)
+	return true
+}
+
+// teeWriter adds w to an existing destination, if any
+func teeWriter(existing io.Writer, w io.Writer) io.Writer {
+	if existing == nil {
+		return w
+	}
+	return io.MultiWriter(existing, w)
+}
@@ -0,0 +1,180 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// shellCmd returns a command running script with sh, skipping the test if sh is unavailable
+func shellCmd(t *testing.T, script string) *exec.Cmd {
+	t.Helper()
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+	return exec.Command(sh, "-c", script)
+}
+
+func TestCaptureCmdStreams(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "cmd.log"})
+
+	cmd := shellCmd(t, `echo "to stdout"; echo "to stderr" >&2; printf "no newline"`)
+	if err := logger.CaptureCmd(cmd, CmdOptions{}).Run(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "cmd.log"))
+	lines := strings.Split(strings.TrimSpace(logStr), "\n")
+
+	var stdoutLine, stderrLine, partialLine, summary string
+	for _, line := range lines {
+		switch {
+		case strings.Contains(line, "to stdout"):
+			stdoutLine = line
+		case strings.Contains(line, "to stderr"):
+			stderrLine = line
+		case strings.Contains(line, "no newline"):
+			partialLine = line
+		case strings.Contains(line, "Process exited"):
+			summary = line
+		}
+	}
+
+	if !strings.Contains(stdoutLine, `"stream":"stdout"`) || !strings.Contains(stdoutLine, `"level":"info"`) {
+		t.Errorf("Unexpected stdout entry: %s", stdoutLine)
+	}
+	if !strings.Contains(stderrLine, `"stream":"stderr"`) || !strings.Contains(stderrLine, `"level":"warn"`) {
+		t.Errorf("Unexpected stderr entry: %s", stderrLine)
+	}
+	if partialLine == "" {
+		t.Error("Expected trailing partial line to be flushed")
+	}
+	if !strings.Contains(summary, `"exit_code":0`) || !strings.Contains(summary, `"duration":`) {
+		t.Errorf("Unexpected exit summary: %s", summary)
+	}
+	for _, line := range []string{stdoutLine, stderrLine, summary} {
+		if !strings.Contains(line, `"cmd":"sh"`) || !strings.Contains(line, `"pid":`) {
+			t.Errorf("Expected cmd and pid fields in %s", line)
+		}
+	}
+}
+
+func TestCaptureCmdCustomLevels(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{Level: "debug", LogDir: tmpDir, Filename: "levels.log"})
+
+	cmd := shellCmd(t, `echo out; echo err >&2`)
+	capture := logger.CaptureCmd(cmd, CmdOptions{StdoutLevel: "debug", StderrLevel: "error"})
+	if err := capture.Run(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "levels.log"))
+	levels := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(logStr), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err == nil {
+			if stream, ok := entry["stream"].(string); ok {
+				levels[stream], _ = entry["level"].(string)
+			}
+		}
+	}
+	if levels["stdout"] != "debug" {
+		t.Errorf("Expected stdout at debug level, got: %s", logStr)
+	}
+	if levels["stderr"] != "error" {
+		t.Errorf("Expected stderr at error level, got: %s", logStr)
+	}
+}
+
+func TestCaptureCmdExitCode(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "exit.log"})
+
+	cmd := shellCmd(t, `exit 3`)
+	err := logger.CaptureCmd(cmd, CmdOptions{}).Run()
+	if err == nil {
+		t.Fatal("Expected error for non-zero exit")
+	}
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "exit.log"))
+	if !strings.Contains(logStr, `"exit_code":3`) {
+		t.Errorf("Expected exit code 3 in summary, got: %s", logStr)
+	}
+	if !strings.Contains(logStr, `"level":"error"`) {
+		t.Errorf("Expected error level summary, got: %s", logStr)
+	}
+}
+
+func TestCaptureCmdStartFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "start.log"})
+
+	cmd := exec.Command(filepath.Join(tmpDir, "does-not-exist"))
+	if err := logger.CaptureCmd(cmd, CmdOptions{}).Run(); err == nil {
+		t.Fatal("Expected start error")
+	}
+	logger.Close()
+
+	if !strings.Contains(readLogFile(t, filepath.Join(tmpDir, "start.log")), "Failed to start process") {
+		t.Error("Expected start failure to be logged")
+	}
+}
+
+func TestCaptureCmdJSONPassthrough(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "json.log"})
+
+	cmd := shellCmd(t, `echo '{"level":"error","msg":"child failed","code":7,"pid":1}'; echo 'not json'`)
+	if err := logger.CaptureCmd(cmd, CmdOptions{JSONPassthrough: true}).Run(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "json.log"))
+	var jsonLine string
+	for _, line := range strings.Split(logStr, "\n") {
+		if strings.Contains(line, "child failed") {
+			jsonLine = line
+		}
+	}
+	if !strings.Contains(jsonLine, `"level":"error"`) || !strings.Contains(jsonLine, `"code":7`) {
+		t.Errorf("Expected child level and fields to pass through, got: %s", jsonLine)
+	}
+	if strings.Contains(jsonLine, `"pid":1,`) || strings.Contains(jsonLine, `"pid":1}`) {
+		t.Errorf("Child JSON must not override the pid field: %s", jsonLine)
+	}
+	if !strings.Contains(logStr, `"message":"not json"`) {
+		t.Error("Expected non-JSON line to be logged as plain text")
+	}
+}
+
+func TestCaptureCmdPreservesExistingWriters(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir})
+	defer logger.Close()
+
+	var raw bytes.Buffer
+	cmd := shellCmd(t, `echo hello`)
+	cmd.Stdout = &raw
+	if err := logger.CaptureCmd(cmd, CmdOptions{}).Run(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if raw.String() != "hello\n" {
+		t.Errorf("Expected existing stdout writer to receive raw output, got %q", raw.String())
+	}
+}
@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ReadKeyFile loads a key file for EncryptionConfig.Keys or
+// PseudonymConfig.Keys, in the format read by ParseKeys
+func ReadKeyFile(path string) (map[string][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseKeys(f)
+}
+
+// ParseKeys parses one "<key id>=<hex key>" pair per line. Blank lines and
+// lines starting with "#" are ignored.
+func ParseKeys(r io.Reader) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, hexKey, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(id) == "" {
+			return nil, fmt.Errorf("line %d: expected <key id>=<hex key>", n)
+		}
+		key, err := hex.DecodeString(strings.TrimSpace(hexKey))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		keys[strings.TrimSpace(id)] = key
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no keys found")
+	}
+	return keys, nil
+}
@@ -0,0 +1,34 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	const keyHex = "30313233343536373839616263646566"
+	keys, err := ParseKeys(strings.NewReader("# comment\n\nv1 = " + keyHex + "\nv2=" + keyHex + "\n"))
+	if err != nil {
+		t.Fatalf("ParseKeys returned error: %v", err)
+	}
+	if len(keys) != 2 || string(keys["v1"]) != "0123456789abcdef" {
+		t.Errorf("Unexpected keys: %v", keys)
+	}
+
+	for _, bad := range []string{"", "# only a comment", "no separator", "=" + keyHex, "v1=zz"} {
+		if _, err := ParseKeys(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "keys.txt")
+	os.WriteFile(path, []byte("k1="+keyHex+"\n"), 0600)
+	if keys, err := ReadKeyFile(path); err != nil || string(keys["k1"]) != "0123456789abcdef" {
+		t.Errorf("ReadKeyFile returned %v, %v", keys, err)
+	}
+	if _, err := ReadKeyFile(path + ".missing"); err == nil {
+		t.Error("Expected error for a missing key file")
+	}
+}
@@ -0,0 +1,189 @@
+package logger
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// truncationMarkerPrefix and truncationMarkerSuffix surround the number
+	// of bytes removed from a shortened value: "…(truncated 123 bytes)"
+	truncationMarkerPrefix = "…(truncated "
+	truncationMarkerSuffix = " bytes)"
+
+	// TruncatedFieldsFieldName records how many fields MaxFields dropped
+	TruncatedFieldsFieldName = "truncated_fields"
+)
+
+// LimitConfig bounds the size of log entries. Oversized values are cut at a
+// UTF-8 boundary and marked with "…(truncated N bytes)". Zero disables a limit.
+type LimitConfig struct {
+	MaxMessageBytes int // Longest message kept
+	MaxFieldBytes   int // Longest field value kept; objects and arrays count their JSON encoding
+	MaxFields       int // Most fields kept, not counting level, time, message and caller
+	MaxEntryBytes   int // Largest encoded entry; the biggest values are shortened first
+}
+
+// enabled reports whether any limit is set
+func (c LimitConfig) enabled() bool {
+	return c.MaxMessageBytes > 0 || c.MaxFieldBytes > 0 || c.MaxFields > 0 || c.MaxEntryBytes > 0
+}
+
+// limitFixedFields are written by the logger itself and never shortened
+var limitFixedFields = map[string]bool{
+	zerolog.LevelFieldName:     true,
+	zerolog.TimestampFieldName: true,
+	zerolog.CallerFieldName:    true,
+}
+
+// limiter applies a LimitConfig to decoded entries
+type limiter struct {
+	cfg   LimitConfig
+	stats *loggerStats
+}
+
+// newLimiter returns a limiter that counts truncations in stats
+func newLimiter(cfg LimitConfig, stats *loggerStats) *limiter {
+	return &limiter{cfg: cfg, stats: stats}
+}
+
+// process implements entryProcessor
+func (lim *limiter) process(e *jsonObject) bool {
+	for i, f := range *e {
+		if limitFixedFields[f.Key] {
+			continue
+		}
+		limit := lim.cfg.MaxFieldBytes
+		if f.Key == zerolog.MessageFieldName {
+			limit = lim.cfg.MaxMessageBytes
+		}
+		if limit <= 0 {
+			continue
+		}
+		if v, ok := truncateValue(f.Value, limit); ok {
+			(*e)[i].Value = v
+			lim.stats.truncated.Add(1)
+		}
+	}
+
+	if lim.cfg.MaxFields > 0 {
+		lim.limitFields(e)
+	}
+	if lim.cfg.MaxEntryBytes > 0 {
+		lim.limitEntry(e)
+	}
+	return true
+}
+
+// limitFields drops the fields past MaxFields and records how many were dropped
+func (lim *limiter) limitFields(e *jsonObject) {
+	kept, dropped := 0, 0
+	out := (*e)[:0]
+	for _, f := range *e {
+		if limitFixedFields[f.Key] || f.Key == zerolog.MessageFieldName {
+			out = append(out, f)
+			continue
+		}
+		if kept < lim.cfg.MaxFields {
+			out = append(out, f)
+			kept++
+			continue
+		}
+		dropped++
+	}
+	*e = out
+	if dropped > 0 {
+		e.set(TruncatedFieldsFieldName, dropped)
+		lim.stats.truncated.Add(1)
+	}
+}
+
+// limitEntry shortens the largest values until the encoded entry fits in
+// MaxEntryBytes or nothing is left to shorten
+func (lim *limiter) limitEntry(e *jsonObject) {
+	size := len(appendJSONValue(nil, *e))
+	if size <= lim.cfg.MaxEntryBytes {
+		return
+	}
+	lim.stats.truncated.Add(1)
+
+	exhausted := make(map[int]bool)
+	for size > lim.cfg.MaxEntryBytes {
+		// Pick the largest value that can still shrink
+		largest, largestSize := -1, 0
+		for i, f := range *e {
+			if limitFixedFields[f.Key] || exhausted[i] {
+				continue
+			}
+			if n := len(appendJSONValue(nil, f.Value)); n > largestSize {
+				largest, largestSize = i, n
+			}
+		}
+		if largest < 0 {
+			return
+		}
+
+		// Leave room for the marker, whose count has at most as many digits
+		// as the length of the text being cut
+		body, _ := splitTruncationMarker(valueString((*e)[largest].Value))
+		marker := len(truncationMarkerPrefix) + len(strconv.Itoa(len(body))) + len(truncationMarkerSuffix)
+		keep := len(body) - (size - lim.cfg.MaxEntryBytes) - marker
+		v, ok := truncateValue((*e)[largest].Value, max(keep, 0))
+		newSize := len(appendJSONValue(nil, v))
+		if !ok || newSize >= largestSize {
+			exhausted[largest] = true
+			continue
+		}
+		(*e)[largest].Value = v
+		size += newSize - largestSize
+	}
+}
+
+// truncateValue shortens v to at most limit bytes of text, reporting false if
+// it already fits. Non-string values are replaced by their shortened JSON text.
+func truncateValue(v interface{}, limit int) (interface{}, bool) {
+	switch v.(type) {
+	case nil, bool:
+		return v, false
+	}
+	s := valueString(v)
+	if len(s) <= limit {
+		return v, false
+	}
+	return truncateString(s, limit), true
+}
+
+// truncateString cuts s to at most limit bytes at a rune boundary and appends
+// the truncation marker. A marker left by an earlier cut is folded into the
+// new one so the count stays accurate.
+func truncateString(s string, limit int) string {
+	body, removed := splitTruncationMarker(s)
+	if len(body) <= limit {
+		return s
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	removed += len(body) - cut
+	return body[:cut] + truncationMarkerPrefix + strconv.Itoa(removed) + truncationMarkerSuffix
+}
+
+// splitTruncationMarker separates a trailing truncation marker from s
+func splitTruncationMarker(s string) (string, int) {
+	if !strings.HasSuffix(s, truncationMarkerSuffix) {
+		return s, 0
+	}
+	i := strings.LastIndex(s, truncationMarkerPrefix)
+	if i < 0 {
+		return s, 0
+	}
+	n, err := strconv.Atoi(s[i+len(truncationMarkerPrefix) : len(s)-len(truncationMarkerSuffix)])
+	if err != nil || n < 0 {
+		return s, 0
+	}
+	return s[:i], n
+}
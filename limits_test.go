@@ -0,0 +1,138 @@
+package logger
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// limitLine runs a single JSON line through a limiter and returns the result
+func limitLine(t *testing.T, cfg LimitConfig, stats *loggerStats, line string) string {
+	t.Helper()
+	e, err := decodeEntry([]byte(line))
+	if err != nil {
+		t.Fatalf("decodeEntry returned error: %v", err)
+	}
+	newLimiter(cfg, stats).process(&e)
+	return strings.TrimSuffix(string(e.encode(nil)), "\n")
+}
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		in    string
+		limit int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"abcdefghij", 4, "abcd…(truncated 6 bytes)"},
+		{"héllo", 2, "h…(truncated 5 bytes)"},
+		{"世界", 4, "世…(truncated 3 bytes)"},
+		{"abc", 0, "…(truncated 3 bytes)"},
+	}
+	for _, tt := range tests {
+		if got := truncateString(tt.in, tt.limit); got != tt.want {
+			t.Errorf("truncateString(%q, %d) = %q, want %q", tt.in, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateStringFoldsMarkers(t *testing.T) {
+	s := strings.Repeat("x", 100)
+	got := truncateString(truncateString(s, 50), 10)
+	if got != strings.Repeat("x", 10)+"…(truncated 90 bytes)" {
+		t.Errorf("Expected a single marker with the total count, got %q", got)
+	}
+}
+
+func TestLimiterMessageAndFields(t *testing.T) {
+	stats := &loggerStats{}
+	cfg := LimitConfig{MaxMessageBytes: 5, MaxFieldBytes: 8}
+
+	got := limitLine(t, cfg, stats, `{"level":"info","time":"2026-01-02T03:04:05Z","body":"0123456789abc","obj":{"k":"0123456789"},"n":42,"ok":true,"message":"hello world"}`)
+
+	for _, want := range []string{
+		`"time":"2026-01-02T03:04:05Z"`,
+		`"body":"01234567…(truncated 5 bytes)"`,
+		`"obj":"{\"k\":\"01…(truncated 10 bytes)"`,
+		`"n":42`,
+		`"ok":true`,
+		`"message":"hello…(truncated 6 bytes)"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %s in %s", want, got)
+		}
+	}
+	if n := stats.truncated.Load(); n != 3 {
+		t.Errorf("Expected 3 truncations, got %d", n)
+	}
+}
+
+func TestLimiterMaxFields(t *testing.T) {
+	stats := &loggerStats{}
+	got := limitLine(t, LimitConfig{MaxFields: 2}, stats, `{"level":"info","a":1,"b":2,"c":3,"d":4,"message":"m"}`)
+
+	if got != `{"level":"info","a":1,"b":2,"message":"m","truncated_fields":2}` {
+		t.Errorf("Unexpected result %s", got)
+	}
+	if stats.truncated.Load() != 1 {
+		t.Errorf("Expected 1 truncation, got %d", stats.truncated.Load())
+	}
+}
+
+func TestLimiterMaxEntryBytes(t *testing.T) {
+	stats := &loggerStats{}
+	line := `{"level":"info","small":"keep me","big":"` + strings.Repeat("b", 5000) + `","bigger":"` + strings.Repeat("c", 8000) + `","message":"` + strings.Repeat("m", 300) + `"}`
+
+	got := limitLine(t, LimitConfig{MaxEntryBytes: 1024}, stats, line)
+
+	if len(got) > 1024 {
+		t.Errorf("Expected entry of at most 1024 bytes, got %d: %s", len(got), got)
+	}
+	if !strings.Contains(got, `"small":"keep me"`) {
+		t.Errorf("Expected small fields to be kept: %s", got)
+	}
+	if !strings.Contains(got, "…(truncated ") {
+		t.Errorf("Expected truncation markers: %s", got)
+	}
+	if stats.truncated.Load() != 1 {
+		t.Errorf("Expected the entry to be counted once, got %d", stats.truncated.Load())
+	}
+}
+
+func TestLimiterEntryTooSmallToFit(t *testing.T) {
+	got := limitLine(t, LimitConfig{MaxEntryBytes: 10}, &loggerStats{}, `{"level":"info","message":"`+strings.Repeat("m", 100)+`"}`)
+	if !strings.HasPrefix(got, `{"level":"info","message":"…(truncated 100 bytes)"`) {
+		t.Errorf("Expected the message to be cut to its marker, got %s", got)
+	}
+}
+
+func TestLoggerLimitsApplyToContextFields(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{
+		LogDir:   tmpDir,
+		Filename: "limits.log",
+		Limits:   LimitConfig{MaxFieldBytes: 16, MaxEntryBytes: 4096},
+	})
+	child := logger.WithFields(map[string]interface{}{"body": strings.Repeat("z", 1<<20)})
+	child.Info().Msg("inherited blob")
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "limits.log"))
+	if len(logStr) > 4096 {
+		t.Errorf("Expected a bounded entry, got %d bytes", len(logStr))
+	}
+	if !strings.Contains(logStr, `"body":"zzzzzzzzzzzzzzzz…(truncated 1048560 bytes)"`) {
+		t.Errorf("Expected inherited field to be truncated: %s", logStr)
+	}
+	if logger.Stats().Truncated != 1 || child.Stats().Truncated != 1 {
+		t.Errorf("Expected the truncation to be counted on shared stats, got %d and %d", logger.Stats().Truncated, child.Stats().Truncated)
+	}
+}
+
+func TestStatsWithoutLimits(t *testing.T) {
+	if !reflect.DeepEqual((&Logger{}).Stats(), Stats{}) {
+		t.Error("Expected zero stats for a logger without counters")
+	}
+}
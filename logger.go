@@ -1,10 +1,14 @@
 package logger
 
 import (
+	"context"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -13,19 +17,64 @@ import (
 // Logger wraps zerolog.Logger with additional functionality
 type Logger struct {
 	zerolog.Logger
-	fileWriter io.Closer // Reference to lumberjack writer for proper cleanup
+	fileWriter    io.Closer       // The file writer, shared by loggers writing the same file
+	file          *fileHandle     // Reference to fileWriter, released by Close
+	closers       []io.Closer     // Resources released by Close before the file writer
+	pseudonymizer *Pseudonymizer  // Keyed pseudonyms for WithPseudonymField, nil when not configured
+	stats         *loggerStats    // Counters shared with derived loggers
+	audit         *auditSink      // Audit log for Audit, nil when not configured
+	syncer        syncer          // Flushes the log file for Sync, nil without one
+	callerSkip    int             // Config.CallerSkip, also used to find call sites for Every, FirstN and Once
+	sites         *sync.Map       // Call site state for Every, FirstN and Once, shared with derived loggers
+	output        io.Writer       // Writer behind the zerolog logger, wrapped by WithBuffer
+	ctx           context.Context // Context bound by Ctx, kept when WithBuffer replaces the writer
+	overrides     *levelOverrides // Level header secret and field rules, nil when not configured
 }
 
+// FileFormat selects how entries are written to the log file
+type FileFormat int
+
+const (
+	// FormatDefault is JSON in Config and Config.FileFormat in ChannelConfig
+	FormatDefault FileFormat = iota
+	// FormatJSON writes one JSON object per line
+	FormatJSON
+	// FormatText writes the human-readable console format without colors
+	FormatText
+)
+
 // Config holds logger configuration
 type Config struct {
-	Level         string      // debug, info, warn, error
-	LogDir        string
-	Filename      string      // Log filename (default: "go.log")
-	MaxSizeMB     int
-	MaxBackups    int
-	Console       bool        // Enable console output
-	DirMode       os.FileMode // Directory permissions (default: 0750)
-	DisableCaller bool        // Disable caller info (file:line) in logs for privacy (default: false/enabled)
+	Level           string // debug, info, warn, error
+	LogDir          string
+	AllowedRoot     string // Directory every log, backup and crash file must stay inside, enforced with os.Root (default: "" unconfined)
+	Filename        string // Log filename (default: "go.log")
+	MaxSizeMB       int
+	MaxBackups      int
+	Console         bool                // Enable console output
+	DirMode         os.FileMode         // Directory permissions (default: 0750)
+	FileMode        os.FileMode         // Log file and backup permissions, applied regardless of umask (default: 0600)
+	FileGroup       string              // Group name or GID owning log files and backups (default: "" unchanged)
+	DisableCaller   bool                // Disable caller info (file:line) in logs for privacy (default: false/enabled)
+	CallerFormat    CallerFormat        // Caller path style: CallerFull (default), CallerModule, CallerBase or CallerFunc
+	CallerFormatter CallerFormatFunc    // Custom caller format, overrides CallerFormat
+	CallerSkip      int                 // Extra stack frames to skip so logging helpers report their own caller
+	TraceSpanEvents bool                // Record error-level entries as events on the active trace span
+	CrashFile       string              // File in LogDir receiving fatal runtime crash output (default: "" disabled)
+	CaptureStdio    bool                // Linux only: redirect process stdout/stderr (fd 1/2) into the log
+	Redact          RedactConfig        // Key rules and detectors for sensitive values (default: disabled)
+	Pseudonym       PseudonymConfig     // HMAC keys and fields for keyed pseudonyms (default: disabled)
+	SanitizeFile    bool                // Also escape control, bidi and invalid UTF-8 characters in file output
+	Limits          LimitConfig         // Size limits for messages, fields and entries (default: unlimited)
+	HashChain       HashChainConfig     // Sequence numbers, chained hashes and signed checkpoints in the log file
+	Encryption      EncryptionConfig    // AES-256-GCM encryption of log files at rest (default: disabled)
+	Audit           AuditConfig         // Separate, synchronously written audit log for Audit (default: disabled)
+	Sync            SyncConfig          // fsync policy for the log file (default: none, left to the OS)
+	Sampling        SamplingConfig      // Per-level, per-message and trace-consistent sampling (default: keep all)
+	Dedup           DedupConfig         // Suppression of repeated identical entries with summaries (default: disabled)
+	LevelOverride   LevelOverrideConfig // Signed level header and field rules overriding Level for derived loggers
+	Route           RouteConfig         // Per-value log files chosen by a field, e.g. one per tenant (default: disabled)
+	FileFormat      FileFormat          // Main log file format: FormatJSON (default) or FormatText
 }
 
 // New creates a new logger instance
@@ -52,58 +101,254 @@ func New(cfg Config) *Logger {
 	cfg.Filename = filepath.Clean(cfg.Filename)
 
 	// Check for path traversal attempts in LogDir
-	if strings.Contains(cfg.LogDir, "..") {
+	if hasParentRef(cfg.LogDir) {
 		// Path traversal detected - fall back to stderr with warning
 		return createStderrLogger("path traversal detected in LogDir: " + cfg.LogDir)
 	}
 
 	// Validate filename doesn't contain path separators
-	if strings.ContainsAny(cfg.Filename, `/\`) || strings.Contains(cfg.Filename, "..") {
+	if !validFilename(cfg.Filename) {
 		// Invalid filename - fall back to stderr with warning
 		return createStderrLogger("invalid filename (contains path separators or traversal): " + cfg.Filename)
 	}
 
-	// Create log directory if it doesn't exist
-	if err := os.MkdirAll(cfg.LogDir, cfg.DirMode); err != nil {
-		// Log the error to stderr using structured logging before falling back
-		stderrLogger := zerolog.New(os.Stderr).With().Timestamp().Logger()
-		stderrLogger.Error().
-			Err(err).
-			Str("log_dir", cfg.LogDir).
-			Msg("Failed to create log directory, falling back to stderr")
+	// The crash file is held to the same rules as the log filename
+	if cfg.CrashFile != "" {
+		cfg.CrashFile = filepath.Clean(cfg.CrashFile)
+		if !validFilename(cfg.CrashFile) {
+			return createStderrLogger("invalid crash filename (contains path separators or traversal): " + cfg.CrashFile)
+		}
+	}
+
+	// Encryption fails closed: without a valid key, nothing is written to disk
+	var encKeys *encryptionKeys
+	if cfg.Encryption.enabled() {
+		var err error
+		if encKeys, err = newEncryptionKeys(cfg.Encryption); err != nil {
+			return createStderrLogger("invalid encryption configuration: " + err.Error())
+		}
+	}
 
-		return &Logger{Logger: stderrLogger}
+	// Confine every file to AllowedRoot when set: the log directory is opened
+	// once as an os.Root and all later file operations go through it
+	var logRoot *os.Root
+	if cfg.AllowedRoot != "" {
+		var err error
+		if logRoot, err = openLogRoot(cfg.AllowedRoot, cfg.LogDir, cfg.DirMode); err != nil {
+			return createStderrLogger("LogDir cannot be opened inside AllowedRoot: " + err.Error())
+		}
+	} else if err := os.MkdirAll(cfg.LogDir, cfg.DirMode); err != nil {
+		// Create log directory if it doesn't exist
+		return createLogDirErrorLogger(err, cfg.LogDir, "Failed to create log directory, falling back to stderr")
+	} else if cfg.FileMode != 0 || cfg.FileGroup != "" || cfg.HashChain.Enabled || encKeys != nil || cfg.Sync.enabled() {
+		// Explicit file permissions, hash chains, encryption and sync policies
+		// need the built-in writer, which works on an os.Root
+		if logRoot, err = os.OpenRoot(cfg.LogDir); err != nil {
+			return createLogDirErrorLogger(err, cfg.LogDir, "Failed to open log directory, falling back to stderr")
+		}
 	}
 
 	// Parse log level (set per-logger, not globally)
 	level := parseLogLevel(cfg.Level)
 
-	// Configure file rotation
-	fileWriter := &lumberjack.Logger{
-		Filename:   filepath.Join(cfg.LogDir, cfg.Filename),
-		MaxSize:    cfg.MaxSizeMB,
-		MaxBackups: cfg.MaxBackups,
-		MaxAge:     30, // days
-		Compress:   false,
+	// Log file permissions are applied with fchmod/fchown, independent of the umask
+	fileMode := cfg.FileMode
+	if fileMode == 0 {
+		fileMode = defaultFileMode
+	}
+	gid := -1
+	var groupErr error
+	if cfg.FileGroup != "" {
+		if gid, groupErr = resolveGroup(cfg.FileGroup); groupErr != nil {
+			gid = -1
+		}
+	}
+
+	// Configure file rotation. Loggers for the same file share one writer.
+	settings := fileSettings{
+		maxSizeMB:  cfg.MaxSizeMB,
+		maxBackups: cfg.MaxBackups,
+		maxAge:     defaultMaxAge,
+		group:      cfg.FileGroup,
+		hashChain:  cfg.HashChain.Enabled,
+		keyID:      cfg.Encryption.KeyID,
+	}
+	if logRoot != nil {
+		settings.mode = fileMode
+	}
+	logDirPath := sharedDir(cfg.AllowedRoot, cfg.LogDir)
+	var looseFiles []looseFile
+	file, reused, err := acquireFile(filepath.Join(logDirPath, cfg.Filename), func() (io.WriteCloser, syncer, error) {
+		if logRoot == nil {
+			path := filepath.Join(cfg.LogDir, cfg.Filename)
+			return &lumberjack.Logger{
+				Filename:   path,
+				MaxSize:    cfg.MaxSizeMB,
+				MaxBackups: cfg.MaxBackups,
+				MaxAge:     30, // days
+				Compress:   false,
+			}, pathSyncer(path), nil
+		}
+
+		looseFiles = findLooseFiles(logRoot, cfg.Filename, fileMode)
+		opts := rotateOptions{
+			maxSize:    int64(cfg.MaxSizeMB) * 1024 * 1024,
+			maxBackups: cfg.MaxBackups,
+			maxAge:     defaultMaxAge,
+			mode:       fileMode,
+			gid:        gid,
+			encrypt:    encKeys,
+			syncClose:  cfg.Sync.enabled(),
+		}
+		open := func(opts rotateOptions) (io.WriteCloser, error) {
+			if cfg.HashChain.Enabled {
+				return newChainWriter(logRoot, cfg.Filename, opts, cfg.HashChain)
+			}
+			return newRotatingWriter(logRoot, cfg.Filename, opts)
+		}
+		rw, err := open(opts)
+		if err != nil && opts.gid >= 0 {
+			// Keep logging with the configured mode if the group change is not permitted
+			groupErr, opts.gid = err, -1
+			rw, err = open(opts)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return rw, rw.(syncer), nil
+	}, settings)
+	if err != nil {
+		if logRoot != nil {
+			_ = logRoot.Close()
+		}
+		// Sharing would write entries without the configured chain or encryption
+		if errors.Is(err, ErrFileConflict) {
+			return createStderrLogger("log file cannot be shared: " + err.Error())
+		}
+		if cfg.AllowedRoot != "" {
+			return createStderrLogger("log file cannot be opened inside AllowedRoot: " + err.Error())
+		}
+		return createLogDirErrorLogger(err, cfg.LogDir, "Failed to open log file, falling back to stderr")
+	}
+	var conflictErr error
+	if reused {
+		conflictErr = file.conflict(settings)
+		if logRoot != nil {
+			// Only the writer that opened the file keeps its root
+			defer logRoot.Close()
+		}
+	}
+	fileWriter, fileSync := file.w, file.sync
+
+	// Claim stdout/stderr before building the console writer so that console
+	// output keeps going to the real terminal instead of into the capture
+	var capture *stdioCapture
+	var captureErr error
+	consoleOut := io.Writer(os.Stdout)
+	if cfg.CaptureStdio {
+		capture, captureErr = newStdioCapture()
+		if capture != nil {
+			consoleOut = capture.console()
+		}
 	}
 
 	// Create multi-writer (file + console if enabled)
 	var writers []io.Writer
-	writers = append(writers, fileWriter)
+	fileOut := io.Writer(fileWriter)
+	if cfg.FileFormat == FormatText {
+		fileOut = newTextFileWriter(fileOut)
+	}
 
-	if cfg.Console {
-		consoleWriter := zerolog.ConsoleWriter{
-			Out:        os.Stdout,
-			TimeFormat: "2006-01-02 15:04:05",
-			NoColor:    false,
+	// Route entries to per-value files; the other file stages apply to both
+	var routeOut *routeWriter
+	var routeErr error
+	if cfg.Route.Field != "" {
+		routeOpts := rotateOptions{
+			maxSize:    int64(cfg.MaxSizeMB) * 1024 * 1024,
+			maxBackups: cfg.MaxBackups,
+			maxAge:     defaultMaxAge,
+			mode:       fileMode,
+			gid:        gid,
+			encrypt:    encKeys,
+			syncClose:  cfg.Sync.enabled(),
+		}
+		if groupErr != nil {
+			routeOpts.gid = -1
+		}
+		openRouted := func(root *os.Root, name string) (io.WriteCloser, error) {
+			if cfg.HashChain.Enabled {
+				return newChainWriter(root, name, routeOpts, cfg.HashChain)
+			}
+			return newRotatingWriter(root, name, routeOpts)
 		}
-		writers = append(writers, consoleWriter)
+		routeOut, routeErr = newRouteWriter(fileOut, cfg.Route, routeOptions{
+			root:     logRoot,
+			logDir:   cfg.LogDir,
+			path:     logDirPath,
+			sync:     fileSync,
+			dirMode:  cfg.DirMode,
+			text:     cfg.FileFormat == FormatText,
+			settings: settings,
+			open:     openRouted,
+		})
+		if routeErr == nil {
+			fileOut, fileSync = routeOut, routeOut
+		}
+	}
+
+	if cfg.SanitizeFile {
+		fileOut = sanitizingWriter{next: fileOut}
+	}
+
+	// Apply the fsync policy to the file output
+	stats := &loggerStats{}
+	var syncOut *syncWriter
+	var syncErr error
+	if cfg.Sync.enabled() {
+		syncOut, syncErr = newSyncWriter(fileOut, fileSync, cfg.Sync, stats)
+		fileOut, fileSync = syncOut, syncOut
+	}
+	writers = append(writers, fileOut)
+
+	if cfg.Console {
+		writers = append(writers, newConsoleWriter(consoleOut))
 	}
 
 	multiWriter := io.MultiWriter(writers...)
 
+	// Pseudonymization fails closed: without a valid key, values are masked
+	var pseudonymizer *Pseudonymizer
+	var pseudonymErr error
+	if cfg.Pseudonym.enabled() {
+		pseudonymizer, pseudonymErr = NewPseudonymizer(cfg.Pseudonym.KeyID, cfg.Pseudonym.Keys)
+	}
+
+	// Rewrite entries before they reach any output
+	var processors []entryProcessor
+	if len(cfg.Pseudonym.Fields) > 0 {
+		processors = append(processors, newPseudonymFieldProcessor(pseudonymizer, cfg.Pseudonym.Fields).process)
+	}
+	if cfg.Redact.enabled() {
+		processors = append(processors, newRedactor(cfg.Redact).process)
+	}
+	// Limits run last so that secrets are redacted before values are cut
+	if cfg.Limits.enabled() {
+		processors = append(processors, newLimiter(cfg.Limits, stats).process)
+	}
+
+	// Deduplicate after the processors, so redacted values are compared
+	var output io.Writer = multiWriter
+	var dedupOut *dedupWriter
+	if cfg.Dedup.Window > 0 {
+		dedupOut = newDedupWriter(output, cfg.Dedup, stats)
+		output = dedupOut
+	}
+	if len(processors) > 0 {
+		output = &processingWriter{next: output, processors: processors}
+	}
+
 	// Create logger context with per-instance level (not global)
-	ctx := zerolog.New(multiWriter).
+	ctx := zerolog.New(output).
 		Level(level).
 		With().
 		Timestamp()
@@ -112,26 +357,200 @@ func New(cfg Config) *Logger {
 	// By default (DisableCaller = false), caller info is included for debugging
 	// Set DisableCaller = true to omit file paths for enhanced privacy/security
 	if !cfg.DisableCaller {
-		ctx = ctx.Caller()
+		ctx = withCaller(ctx, cfg)
 	}
 
 	logger := ctx.Logger()
 
-	return &Logger{
-		Logger:     logger,
-		fileWriter: fileWriter, // Store for proper cleanup on Close()
+	// Mirror error-level entries onto the active trace span when requested
+	if cfg.TraceSpanEvents {
+		logger = logger.Hook(spanEventHook{})
 	}
+
+	// Sample after span events, so sampled-out errors still reach the span
+	var samplingErr error
+	if len(cfg.Sampling.Levels) > 0 {
+		var hook *sampleHook
+		hook, samplingErr = newSampleHook(cfg.Sampling)
+		stats.sampling = hook
+		logger = logger.Hook(hook)
+	}
+
+	// Validated before the logger exists, reported once it does
+	var overrides *levelOverrides
+	var overrideErr error
+	if cfg.LevelOverride.enabled() {
+		overrides, overrideErr = newLevelOverrides(cfg.LevelOverride)
+	}
+
+	l := &Logger{
+		Logger:        logger,
+		fileWriter:    fileWriter,
+		file:          file, // Released on Close()
+		pseudonymizer: pseudonymizer,
+		stats:         stats,
+		syncer:        fileSync,
+		callerSkip:    cfg.CallerSkip,
+		sites:         new(sync.Map),
+		output:        output,
+		overrides:     overrides,
+	}
+
+	if groupErr != nil {
+		l.Error().
+			Err(groupErr).
+			Str("group", cfg.FileGroup).
+			Msg("Failed to set log file group")
+	}
+	if conflictErr != nil {
+		l.Error().
+			Err(conflictErr).
+			Msg("Log file is already open with different settings, sharing the existing writer")
+	}
+	for _, f := range looseFiles {
+		l.Warn().
+			Str("file", f.name).
+			Str("mode", f.mode.String()).
+			Str("file_mode", fileMode.String()).
+			Msg("Existing log file has looser permissions than FileMode")
+	}
+
+	// Audit entries fail loudly instead of falling back to another output
+	if cfg.Audit.Filename != "" {
+		l.audit = openAuditSink(cfg, encKeys)
+		if l.audit.err != nil {
+			l.Error().
+				Err(l.audit.err).
+				Str("audit_file", cfg.Audit.Filename).
+				Msg("Failed to open audit log, audit entries will return errors")
+		}
+		if l.audit.conflict != nil {
+			l.Error().
+				Err(l.audit.conflict).
+				Str("audit_file", cfg.Audit.Filename).
+				Msg("Audit file is already open with different settings, sharing the existing writer")
+		}
+	}
+
+	if samplingErr != nil {
+		l.Error().
+			Err(samplingErr).
+			Msg("Invalid sampling configuration, affected rules are off")
+	}
+
+	if overrideErr != nil {
+		l.Error().
+			Err(overrideErr).
+			Msg("Invalid level override configuration, affected overrides are off")
+	}
+
+	if pseudonymErr != nil {
+		l.Error().
+			Err(pseudonymErr).
+			Str("key_id", cfg.Pseudonym.KeyID).
+			Msg("Invalid pseudonym configuration, pseudonymized values will be masked")
+	}
+
+	if capture != nil {
+		if err := capture.start(l); err != nil {
+			_ = capture.Close()
+			captureErr = err
+		} else {
+			l.closers = append(l.closers, capture)
+		}
+	}
+	if captureErr != nil {
+		l.Error().
+			Err(captureErr).
+			Msg("Failed to capture stdout/stderr")
+	}
+
+	// Closed after the capture, so its last entries are synced too
+	if dedupOut != nil {
+		l.closers = append(l.closers, dedupOut)
+	}
+	if routeOut != nil {
+		l.closers = append(l.closers, routeOut)
+	}
+	if routeErr != nil {
+		l.Error().
+			Err(routeErr).
+			Str("route_field", cfg.Route.Field).
+			Msg("Failed to set up log routing, routed entries go to the main log file")
+	}
+	if syncOut != nil {
+		l.closers = append(l.closers, syncOut)
+	}
+	if syncErr != nil {
+		l.Error().
+			Err(syncErr).
+			Str("sync_level", cfg.Sync.Level).
+			Msg("Invalid sync level, level-based syncing is off")
+	}
+
+	// Route fatal runtime errors (unrecovered panics, throws) to the crash file
+	if cfg.CrashFile != "" {
+		if err := setCrashOutput(logRoot, cfg.LogDir, cfg.CrashFile); err != nil {
+			l.Error().
+				Err(err).
+				Str("crash_file", cfg.CrashFile).
+				Msg("Failed to set crash output")
+		}
+	}
+
+	return l
+}
+
+// validFilename reports whether name is a plain filename without path
+// separators or traversal sequences
+func validFilename(name string) bool {
+	return !strings.ContainsAny(name, `/\`) && name != "." && name != ".."
+}
+
+// createLogDirErrorLogger reports a log directory error on stderr and returns
+// a logger that writes to stderr. Stderr is the original one if a capture
+// is active.
+func createLogDirErrorLogger(err error, logDir, msg string) *Logger {
+	stderrLogger := zerolog.New(stderrWriter{}).With().Timestamp().Logger()
+	stderrLogger.Error().
+		Err(err).
+		Str("log_dir", logDir).
+		Msg(msg)
+
+	return &Logger{Logger: stderrLogger, sites: new(sync.Map)}
 }
 
 // createStderrLogger creates a logger that writes to stderr with a security warning
 func createStderrLogger(warningMsg string) *Logger {
 	// Log security warning to stderr
-	stderrLogger := zerolog.New(os.Stderr).With().Timestamp().Logger()
+	stderrLogger := zerolog.New(stderrWriter{}).With().Timestamp().Logger()
 	stderrLogger.Error().
 		Str("security_warning", warningMsg).
 		Msg("SECURITY: Invalid logger configuration, falling back to stderr")
 
-	return &Logger{Logger: stderrLogger}
+	return &Logger{Logger: stderrLogger, sites: new(sync.Map)}
+}
+
+// newTextFileWriter returns the FormatText file writer: the console format
+// with full timestamps and without colors
+func newTextFileWriter(out io.Writer) zerolog.ConsoleWriter {
+	return zerolog.ConsoleWriter{
+		Out:           out,
+		TimeFormat:    time.RFC3339,
+		NoColor:       true,
+		FormatPrepare: sanitizeConsoleEvent,
+	}
+}
+
+// newConsoleWriter returns the human-readable console writer. Entries are
+// sanitized so that logged values cannot inject terminal escapes or forge lines.
+func newConsoleWriter(out io.Writer) zerolog.ConsoleWriter {
+	return zerolog.ConsoleWriter{
+		Out:           out,
+		TimeFormat:    "2006-01-02 15:04:05",
+		NoColor:       false,
+		FormatPrepare: sanitizeConsoleEvent,
+	}
 }
 
 // parseLogLevel converts string log level to zerolog level
@@ -152,40 +571,72 @@ func parseLogLevel(level string) zerolog.Level {
 
 // Close closes the logger and flushes any buffered logs
 func (l *Logger) Close() error {
-	// Close the underlying file writer to ensure all logs are flushed
-	if l.fileWriter != nil {
-		return l.fileWriter.Close()
+	// Release resources that may still produce entries, such as captured
+	// stdout/stderr, while the file writer is open
+	var errs []error
+	for _, c := range l.closers {
+		errs = append(errs, c.Close())
 	}
-	return nil
+
+	// Close the underlying file writer to ensure all logs are flushed, unless
+	// another logger still writes the same file
+	if l.file != nil {
+		errs = append(errs, l.file.Close())
+	}
+	errs = append(errs, l.audit.Close())
+	return errors.Join(errs...)
 }
 
-// WithField adds a field to the logger
+// WithField adds a field to the logger. Struct values honor log struct tags
+// (see LogValue).
 func (l *Logger) WithField(key string, value interface{}) *Logger {
-	newLogger := l.Logger.With().Interface(key, value).Logger()
-	return &Logger{
-		Logger:     newLogger,
-		fileWriter: l.fileWriter, // Preserve fileWriter reference
+	zl := l.Logger.With().Interface(key, LogValue(value)).Logger()
+	if level, ok := l.overrides.ruleLevel(key, value); ok {
+		zl = zl.Level(level)
 	}
+	return l.derive(zl)
 }
 
-// WithFields adds multiple fields to the logger
+// WithFields adds multiple fields to the logger. Struct values honor log
+// struct tags (see LogValue).
 func (l *Logger) WithFields(fields map[string]interface{}) *Logger {
 	ctx := l.Logger.With()
+	var level zerolog.Level
+	matched := false
 	for k, v := range fields {
-		ctx = ctx.Interface(k, v)
+		ctx = ctx.Interface(k, LogValue(v))
+		// The most verbose matching rule wins, whatever the map order
+		if lv, ok := l.overrides.ruleLevel(k, v); ok && (!matched || lv < level) {
+			level, matched = lv, true
+		}
 	}
-	newLogger := ctx.Logger()
-	return &Logger{
-		Logger:     newLogger,
-		fileWriter: l.fileWriter, // Preserve fileWriter reference
+	zl := ctx.Logger()
+	if matched {
+		zl = zl.Level(level)
 	}
+	return l.derive(zl)
 }
 
 // WithError adds an error to the logger context
 func (l *Logger) WithError(err error) *Logger {
-	newLogger := l.Logger.With().Err(err).Logger()
+	return l.derive(l.Logger.With().Err(err).Logger())
+}
+
+// derive wraps a child zerolog.Logger, preserving the resources shared with l
+func (l *Logger) derive(zl zerolog.Logger) *Logger {
 	return &Logger{
-		Logger:     newLogger,
-		fileWriter: l.fileWriter, // Preserve fileWriter reference
+		Logger:        zl,
+		fileWriter:    l.fileWriter, // Preserve fileWriter reference
+		file:          l.file,
+		closers:       l.closers,
+		pseudonymizer: l.pseudonymizer,
+		stats:         l.stats,
+		audit:         l.audit,
+		syncer:        l.syncer,
+		callerSkip:    l.callerSkip,
+		sites:         l.sites,
+		output:        l.output,
+		ctx:           l.ctx,
+		overrides:     l.overrides,
 	}
 }
@@ -1115,3 +1115,30 @@ func TestWithErrorPreservesFileWriter(t *testing.T) {
 		t.Error("Expected error message to be present")
 	}
 }
+
+// newTestLogger returns a logger for cfg writing to test.log in a new
+// directory, and the path of that file
+func newTestLogger(t *testing.T, cfg Config) (*Logger, string) {
+	t.Helper()
+	cfg.LogDir, cfg.Filename = t.TempDir(), "test.log"
+	return New(cfg), filepath.Join(cfg.LogDir, cfg.Filename)
+}
+
+// readLogFile returns the content of a log file, failing the test on error
+func readLogFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	return string(content)
+}
+
+// readLogFileIfExists returns the content of a log file, or "" if it does not exist
+func readLogFileIfExists(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(content)
+}
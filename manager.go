@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ManagerConfig defines named log channels that share one Config
+type ManagerConfig struct {
+	// Config holds the settings shared by every channel, such as LogDir,
+	// DirMode, rotation and redaction. Its Level and FileFormat are defaults
+	// that channels override; its Filename is not used.
+	Config
+
+	Channels map[string]ChannelConfig // Channels by name, e.g. "app", "access", "error"
+
+	// Primary is the channel that also receives the process-wide CaptureStdio
+	// and CrashFile settings, the Audit log and Route, which cannot be shared.
+	// (default: the first channel name in sorted order)
+	Primary string
+}
+
+// ChannelConfig holds the settings of one channel
+type ChannelConfig struct {
+	Filename string     // Log file name (default: "<channel name>.log")
+	Level    string     // Level of the channel (default: Config.Level)
+	Format   FileFormat // File format of the channel (default: Config.FileFormat)
+}
+
+// Manager owns the loggers of a set of named channels
+type Manager struct {
+	loggers map[string]*Logger
+}
+
+// NewManager creates a logger for every channel of cfg. It returns an error
+// if cfg has no channels, Primary names no channel or two channels share a
+// file. Other problems are handled per channel as by New.
+func NewManager(cfg ManagerConfig) (*Manager, error) {
+	if len(cfg.Channels) == 0 {
+		return nil, errors.New("logger manager needs at least one channel")
+	}
+	names := make([]string, 0, len(cfg.Channels))
+	for name := range cfg.Channels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	primary := cfg.Primary
+	if primary == "" {
+		primary = names[0]
+	} else if _, ok := cfg.Channels[primary]; !ok {
+		return nil, fmt.Errorf("primary channel %q is not defined", primary)
+	}
+
+	configs := make(map[string]Config, len(names))
+	files := make(map[string]string, len(names))
+	for _, name := range names {
+		ch := cfg.Channels[name]
+		c := cfg.Config
+		c.Filename = ch.Filename
+		if c.Filename == "" {
+			c.Filename = name + ".log"
+		}
+		if ch.Level != "" {
+			c.Level = ch.Level
+		}
+		if ch.Format != FormatDefault {
+			c.FileFormat = ch.Format
+		}
+		if name != primary {
+			c.CaptureStdio, c.CrashFile, c.Audit, c.Route = false, "", AuditConfig{}, RouteConfig{}
+		}
+
+		// Channels share LogDir, so their files must differ
+		file := strings.ToLower(filepath.Clean(c.Filename))
+		if other, ok := files[file]; ok {
+			return nil, fmt.Errorf("channels %q and %q share the log file %s", other, name, c.Filename)
+		}
+		files[file] = name
+		configs[name] = c
+	}
+
+	m := &Manager{loggers: make(map[string]*Logger, len(names))}
+	for _, name := range names {
+		m.loggers[name] = New(configs[name])
+	}
+	return m, nil
+}
+
+// Get returns the logger of the named channel, or nil if there is none
+func (m *Manager) Get(name string) *Logger {
+	return m.loggers[name]
+}
+
+// Close closes the logger of every channel
+func (m *Manager) Close() error {
+	var errs []error
+	for _, l := range m.loggers {
+		errs = append(errs, l.Close())
+	}
+	return errors.Join(errs...)
+}
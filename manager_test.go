@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManagerChannels(t *testing.T) {
+	tmpDir := t.TempDir()
+	m, err := NewManager(ManagerConfig{
+		Config: Config{LogDir: tmpDir, Level: "info", DirMode: 0700},
+		Channels: map[string]ChannelConfig{
+			"app":    {},
+			"access": {Filename: "http.log", Format: FormatText},
+			"error":  {Level: "error"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+	m.Get("app").Debug().Msg("app debug")
+	m.Get("app").Info().Msg("app info")
+	m.Get("access").Info().Str("path", "/health").Msg("GET")
+	m.Get("error").Warn().Msg("error warn")
+	m.Get("error").Error().Msg("error error")
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	appStr := readLogFile(t, filepath.Join(tmpDir, "app.log"))
+	if strings.Contains(appStr, "app debug") || !strings.Contains(appStr, `"message":"app info"`) {
+		t.Errorf("Expected the shared level in app.log: %s", appStr)
+	}
+	accessStr := readLogFile(t, filepath.Join(tmpDir, "http.log"))
+	if strings.HasPrefix(accessStr, "{") || !strings.Contains(accessStr, "GET") || !strings.Contains(accessStr, "path=") {
+		t.Errorf("Expected text output in http.log: %s", accessStr)
+	}
+	errorStr := readLogFile(t, filepath.Join(tmpDir, "error.log"))
+	if strings.Contains(errorStr, "error warn") || !strings.Contains(errorStr, "error error") {
+		t.Errorf("Expected the channel level in error.log: %s", errorStr)
+	}
+	if m.Get("missing") != nil {
+		t.Error("Expected nil for an unknown channel")
+	}
+}
+
+func TestManagerPrimaryOnlySettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	m, err := NewManager(ManagerConfig{
+		Config:   Config{LogDir: tmpDir, Audit: AuditConfig{Filename: "audit.log"}},
+		Channels: map[string]ChannelConfig{"app": {}, "access": {}},
+		Primary:  "app",
+	})
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+	defer m.Close()
+
+	if err := m.Get("app").Audit().Actor("u").Action("a").Target("t").Outcome(AuditSuccess).Msg("audited"); err != nil {
+		t.Errorf("Expected the primary channel to audit, got %v", err)
+	}
+	if err := m.Get("access").Audit().Actor("u").Action("a").Target("t").Outcome(AuditSuccess).Msg("audited"); err != ErrAuditDisabled {
+		t.Errorf("Expected ErrAuditDisabled on other channels, got %v", err)
+	}
+}
+
+func TestManagerInvalidConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	for name, cfg := range map[string]ManagerConfig{
+		"no channels":     {Config: Config{LogDir: tmpDir}},
+		"shared file":     {Config: Config{LogDir: tmpDir}, Channels: map[string]ChannelConfig{"app": {}, "other": {Filename: "App.log"}}},
+		"unknown primary": {Config: Config{LogDir: tmpDir}, Channels: map[string]ChannelConfig{"app": {}}, Primary: "main"},
+	} {
+		if m, err := NewManager(cfg); err == nil {
+			m.Close()
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if entries, _ := os.ReadDir(tmpDir); len(entries) != 0 {
+		t.Errorf("Expected no files for invalid configs, got %v", entries)
+	}
+}
+
+func TestManagerChannelFormatOverride(t *testing.T) {
+	tmpDir := t.TempDir()
+	m, err := NewManager(ManagerConfig{
+		Config: Config{LogDir: tmpDir, FileFormat: FormatText},
+		Channels: map[string]ChannelConfig{
+			"app":    {},
+			"events": {Format: FormatJSON},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+	m.Get("app").Info().Msg("text entry")
+	m.Get("events").Info().Msg("json entry")
+	m.Close()
+
+	if appStr := readLogFile(t, filepath.Join(tmpDir, "app.log")); strings.HasPrefix(appStr, "{") {
+		t.Errorf("Expected the shared text format in app.log: %s", appStr)
+	}
+	if eventsStr := readLogFile(t, filepath.Join(tmpDir, "events.log")); !strings.Contains(eventsStr, `"message":"json entry"`) {
+		t.Errorf("Expected JSON in events.log: %s", eventsStr)
+	}
+}
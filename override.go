@@ -0,0 +1,181 @@
+package logger
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultLevelHeader is the HTTP header read by ContextFromRequest by default
+const DefaultLevelHeader = "X-Log-Level"
+
+// minLevelSecretLen is the minimum HMAC secret length in bytes
+const minLevelSecretLen = 16
+
+var (
+	// ErrLevelHeader is returned for a level header that is malformed, badly
+	// signed or expired
+	ErrLevelHeader = errors.New("invalid log level header")
+
+	// ErrLevelSecret is reported when LevelOverrideConfig.Secret is too short
+	ErrLevelSecret = errors.New("level header secret shorter than 16 bytes")
+)
+
+// LevelOverrideConfig lets derived loggers log at another level than the
+// instance, for example at debug level for one customer during an incident.
+// The override applies to every logger derived from the overridden one.
+type LevelOverrideConfig struct {
+	Secret []byte      // HMAC-SHA256 key verifying the level header; empty ignores the header
+	Header string      // Header carrying a signed level (default: "X-Log-Level")
+	Rules  []LevelRule // Field values that set the level of loggers derived with WithField or WithFields
+}
+
+// enabled reports whether any override is configured
+func (c LevelOverrideConfig) enabled() bool {
+	return len(c.Secret) > 0 || len(c.Rules) > 0
+}
+
+// LevelRule sets the level of loggers given a field value
+type LevelRule struct {
+	Field string // Field name, e.g. "tenant"
+	Value string // Field value, compared with the value formatted by fmt.Sprint
+	Level string // Level of matching loggers, e.g. "debug"
+}
+
+// levelOverrides is the validated LevelOverrideConfig
+type levelOverrides struct {
+	secret []byte // nil when the header is ignored
+	header string
+	rules  map[string]map[string]zerolog.Level // Level by field name and value
+}
+
+// newLevelOverrides validates cfg. Invalid parts are left out and reported
+// in the returned error.
+func newLevelOverrides(cfg LevelOverrideConfig) (*levelOverrides, error) {
+	o := &levelOverrides{header: cfg.Header, rules: make(map[string]map[string]zerolog.Level)}
+	if o.header == "" {
+		o.header = DefaultLevelHeader
+	}
+
+	var errs []error
+	if len(cfg.Secret) >= minLevelSecretLen {
+		o.secret = cfg.Secret
+	} else if len(cfg.Secret) > 0 {
+		errs = append(errs, ErrLevelSecret)
+	}
+	for _, rule := range cfg.Rules {
+		level, err := zerolog.ParseLevel(rule.Level)
+		if err != nil || rule.Level == "" {
+			errs = append(errs, fmt.Errorf("unknown level %q in rule for %s=%s", rule.Level, rule.Field, rule.Value))
+			continue
+		}
+		if o.rules[rule.Field] == nil {
+			o.rules[rule.Field] = make(map[string]zerolog.Level)
+		}
+		o.rules[rule.Field][rule.Value] = level
+	}
+	return o, errors.Join(errs...)
+}
+
+// ruleLevel returns the level set by a rule matching key and value
+func (o *levelOverrides) ruleLevel(key string, value interface{}) (zerolog.Level, bool) {
+	if o == nil {
+		return 0, false
+	}
+	values, ok := o.rules[key]
+	if !ok {
+		return 0, false
+	}
+	level, ok := values[fmt.Sprint(value)]
+	return level, ok
+}
+
+// levelContextKey is the context key of the level set by ContextWithLevel
+type levelContextKey struct{}
+
+// ContextWithLevel returns a copy of ctx that makes Logger.Ctx return loggers
+// logging at level, overriding the instance level
+func ContextWithLevel(ctx context.Context, level zerolog.Level) context.Context {
+	return context.WithValue(ctx, levelContextKey{}, level)
+}
+
+// levelFromContext returns the level set by ContextWithLevel
+func levelFromContext(ctx context.Context) (zerolog.Level, bool) {
+	level, ok := ctx.Value(levelContextKey{}).(zerolog.Level)
+	return level, ok
+}
+
+// SignLevelHeader returns a level header value that ContextFromRequest
+// accepts until expires, for loggers configured with the same secret
+func SignLevelHeader(secret []byte, level zerolog.Level, expires time.Time) string {
+	payload := level.String() + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + levelSignature(secret, payload)
+}
+
+// levelSignature returns the base64url HMAC-SHA256 of a header payload
+func levelSignature(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify parses a "<level>.<expiry>.<signature>" header value
+func (o *levelOverrides) verify(value string, now time.Time) (zerolog.Level, error) {
+	payload, sig, ok := cutLast(value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(levelSignature(o.secret, payload))) {
+		return 0, fmt.Errorf("%w: bad signature", ErrLevelHeader)
+	}
+	name, expiry, _ := strings.Cut(payload, ".")
+	exp, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: bad expiry", ErrLevelHeader)
+	}
+	if now.Unix() > exp {
+		return 0, fmt.Errorf("%w: expired", ErrLevelHeader)
+	}
+	level, err := zerolog.ParseLevel(name)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrLevelHeader, err)
+	}
+	return level, nil
+}
+
+// cutLast slices s around the last instance of sep
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// ContextFromRequest returns the request's context. When the request carries
+// a valid signed level header (see SignLevelHeader), the level is attached
+// as with ContextWithLevel, so loggers from Ctx log at that level for the
+// rest of the request. Invalid headers are ignored and reported at most once
+// a minute.
+func (l *Logger) ContextFromRequest(r *http.Request) context.Context {
+	ctx := r.Context()
+	o := l.overrides
+	if o == nil || o.secret == nil {
+		return ctx
+	}
+	value := r.Header.Get(o.header)
+	if value == "" {
+		return ctx
+	}
+	level, err := o.verify(value, time.Now())
+	if err != nil {
+		l.Every(time.Minute).Warn().Err(err).Str("header", o.header).Msg("Ignoring invalid log level header")
+		return ctx
+	}
+	return ContextWithLevel(ctx, level)
+}
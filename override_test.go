@@ -0,0 +1,133 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var testLevelSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestContextWithLevel(t *testing.T) {
+	logger, path := newTestLogger(t, Config{Level: "info"})
+	ctx := ContextWithLevel(context.Background(), zerolog.DebugLevel)
+
+	reqLog := logger.Ctx(ctx)
+	reqLog.Debug().Msg("escalated")
+	reqLog.WithField("step", 2).Debug().Msg("escalated child")
+	logger.Debug().Msg("instance level")
+	logger.Ctx(context.Background()).Debug().Msg("other request")
+	logger.Close()
+
+	logStr := readLogFile(t, path)
+	if !strings.Contains(logStr, `"message":"escalated"`) || !strings.Contains(logStr, "escalated child") {
+		t.Errorf("Expected debug entries in the escalated scope: %s", logStr)
+	}
+	if strings.Contains(logStr, "instance level") || strings.Contains(logStr, "other request") {
+		t.Errorf("Did not expect debug entries outside the scope: %s", logStr)
+	}
+}
+
+func TestLevelHeader(t *testing.T) {
+	logger, path := newTestLogger(t, Config{Level: "info", LevelOverride: LevelOverrideConfig{Secret: testLevelSecret}})
+	valid := SignLevelHeader(testLevelSecret, zerolog.DebugLevel, time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"valid", valid, true},
+		{"missing", "", false},
+		{"wrong secret", SignLevelHeader([]byte("another secret of 32 bytes......"), zerolog.DebugLevel, time.Now().Add(time.Hour)), false},
+		{"tampered level", "trace" + strings.TrimPrefix(valid, "debug"), false},
+		{"expired", SignLevelHeader(testLevelSecret, zerolog.DebugLevel, time.Now().Add(-time.Minute)), false},
+		{"garbage", "debug", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				r.Header.Set(DefaultLevelHeader, tt.header)
+			}
+			logger.Ctx(logger.ContextFromRequest(r)).Debug().Msg("request " + tt.name)
+		})
+	}
+	logger.Close()
+
+	logStr := readLogFile(t, path)
+	for _, tt := range tests {
+		if got := strings.Contains(logStr, `"message":"request `+tt.name+`"`); got != tt.want {
+			t.Errorf("%s: expected debug entry %v, got %v", tt.name, tt.want, got)
+		}
+	}
+	// Reported once, however many invalid headers arrive
+	if got := strings.Count(logStr, "Ignoring invalid log level header"); got != 1 {
+		t.Errorf("Expected one report of invalid headers, got %d: %s", got, logStr)
+	}
+}
+
+func TestLevelHeaderVerify(t *testing.T) {
+	o, err := newLevelOverrides(LevelOverrideConfig{Secret: testLevelSecret})
+	if err != nil {
+		t.Fatalf("newLevelOverrides returned error: %v", err)
+	}
+	now := time.Unix(1800000000, 0)
+	level, err := o.verify(SignLevelHeader(testLevelSecret, zerolog.TraceLevel, now), now)
+	if err != nil || level != zerolog.TraceLevel {
+		t.Errorf("Expected trace level until expiry, got %v, %v", level, err)
+	}
+	if _, err := o.verify(SignLevelHeader(testLevelSecret, zerolog.TraceLevel, now), now.Add(time.Second)); !errors.Is(err, ErrLevelHeader) {
+		t.Errorf("Expected ErrLevelHeader after expiry, got %v", err)
+	}
+}
+
+func TestLevelHeaderWithoutSecret(t *testing.T) {
+	logger, path := newTestLogger(t, Config{Level: "info"})
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(DefaultLevelHeader, SignLevelHeader(nil, zerolog.DebugLevel, time.Now().Add(time.Hour)))
+	logger.Ctx(logger.ContextFromRequest(r)).Debug().Msg("unsigned")
+	logger.Close()
+
+	if logStr := readLogFileIfExists(path); strings.Contains(logStr, "unsigned") {
+		t.Errorf("Expected the header to be ignored without a secret: %s", logStr)
+	}
+}
+
+func TestLevelRules(t *testing.T) {
+	logger, path := newTestLogger(t, Config{Level: "info", LevelOverride: LevelOverrideConfig{Rules: []LevelRule{
+		{Field: "tenant", Value: "acme", Level: "debug"},
+		{Field: "user", Value: "42", Level: "trace"},
+		{Field: "tenant", Value: "noisy", Level: "error"},
+	}}})
+	logger.WithField("tenant", "acme").WithField("op", "sync").Debug().Msg("acme debug")
+	logger.WithField("tenant", "other").Debug().Msg("other debug")
+	logger.WithFields(map[string]interface{}{"tenant": "acme", "user": 42}).Trace().Msg("most verbose wins")
+	logger.WithField("tenant", "noisy").Warn().Msg("noisy warn")
+	logger.Close()
+
+	logStr := readLogFile(t, path)
+	for msg, want := range map[string]bool{"acme debug": true, "other debug": false, "most verbose wins": true, "noisy warn": false} {
+		if strings.Contains(logStr, msg) != want {
+			t.Errorf("Expected %q written: %v, got %s", msg, want, logStr)
+		}
+	}
+}
+
+func TestLevelOverrideInvalidConfig(t *testing.T) {
+	logger, path := newTestLogger(t, Config{Level: "info", LevelOverride: LevelOverrideConfig{
+		Secret: []byte("short"),
+		Rules:  []LevelRule{{Field: "tenant", Value: "acme", Level: "loud"}},
+	}})
+	logger.Close()
+
+	logStr := readLogFile(t, path)
+	if !strings.Contains(logStr, "Invalid level override configuration") || !strings.Contains(logStr, ErrLevelSecret.Error()) || !strings.Contains(logStr, `unknown level \"loud\"`) {
+		t.Errorf("Expected both problems to be reported: %s", logStr)
+	}
+}
@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"io/fs"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// looseFile is an existing log file whose permissions grant more than FileMode
+type looseFile struct {
+	name string
+	mode os.FileMode
+}
+
+// resolveGroup returns the GID for a group name or numeric GID
+func resolveGroup(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil && gid >= 0 {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return -1, err
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+// findLooseFiles lists the log file and its backups in root whose permission
+// bits go beyond mode
+func findLooseFiles(root *os.Root, name string, mode os.FileMode) []looseFile {
+	var loose []looseFile
+	check := func(n string) {
+		info, err := root.Lstat(n)
+		if err != nil || !info.Mode().IsRegular() {
+			return
+		}
+		if perm := info.Mode().Perm(); perm&^mode != 0 {
+			loose = append(loose, looseFile{name: n, mode: perm})
+		}
+	}
+
+	check(name)
+	dir, err := root.Open(".")
+	if err != nil {
+		return loose
+	}
+	defer dir.Close()
+	entries, _ := dir.ReadDir(-1)
+	for _, e := range entries {
+		if _, ok := backupTime(name, e.Name()); ok && e.Type()&fs.ModeType == 0 {
+			check(e.Name())
+		}
+	}
+	return loose
+}
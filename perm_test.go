@@ -0,0 +1,28 @@
+package logger
+
+import (
+	"os/user"
+	"strconv"
+	"testing"
+)
+
+func TestResolveGroup(t *testing.T) {
+	if gid, err := resolveGroup("1234"); err != nil || gid != 1234 {
+		t.Errorf("resolveGroup(\"1234\") = %d, %v", gid, err)
+	}
+	if _, err := resolveGroup("no-such-group-for-logger-tests"); err == nil {
+		t.Error("Expected an error for an unknown group")
+	}
+
+	current, err := user.Current()
+	if err != nil {
+		t.Skip("current user unavailable")
+	}
+	g, err := user.LookupGroupId(current.Gid)
+	if err != nil {
+		t.Skip("group lookup unavailable")
+	}
+	if gid, err := resolveGroup(g.Name); err != nil || strconv.Itoa(gid) != g.Gid {
+		t.Errorf("resolveGroup(%q) = %d, %v, want %s", g.Name, gid, err, g.Gid)
+	}
+}
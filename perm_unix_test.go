@@ -0,0 +1,139 @@
+//go:build unix
+
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// withUmask runs fn with the process umask set to mask
+func withUmask(mask int, fn func()) {
+	old := syscall.Umask(mask)
+	defer syscall.Umask(old)
+	fn()
+}
+
+// fileInfo returns the permission bits and group of path
+func fileInfo(t *testing.T, path string) (os.FileMode, int) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat(%s) returned error: %v", path, err)
+	}
+	return info.Mode().Perm(), int(info.Sys().(*syscall.Stat_t).Gid)
+}
+
+func TestFileModeIgnoresUmask(t *testing.T) {
+	for _, mask := range []int{0, 0077} {
+		t.Run("umask "+strconv.FormatInt(int64(mask), 8), func(t *testing.T) {
+			tmpDir := t.TempDir()
+			withUmask(mask, func() {
+				logger := New(Config{LogDir: tmpDir, Filename: "mode.log", FileMode: 0640})
+				logger.Info().Msg("mode")
+				logger.fileWriter.(*rotatingWriter).Rotate()
+				logger.Info().Msg("after rotation")
+				logger.Close()
+			})
+
+			entries, _ := os.ReadDir(tmpDir)
+			if len(entries) != 2 {
+				t.Fatalf("Expected the log file and one backup, got %v", entries)
+			}
+			for _, e := range entries {
+				if perm, _ := fileInfo(t, filepath.Join(tmpDir, e.Name())); perm != 0640 {
+					t.Errorf("Expected %s to have mode 0640 under umask %o, got %o", e.Name(), mask, perm)
+				}
+			}
+		})
+	}
+}
+
+func TestFileModeTightensExistingFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "existing.log")
+	if err := os.WriteFile(path, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	os.Chmod(path, 0666)
+
+	logger := New(Config{LogDir: tmpDir, Filename: "existing.log", FileMode: 0600})
+	logger.Close()
+
+	if perm, _ := fileInfo(t, path); perm != 0600 {
+		t.Errorf("Expected existing file to be set to 0600, got %o", perm)
+	}
+	logStr := readLogFile(t, path)
+	if !strings.Contains(logStr, "Existing log file has looser permissions than FileMode") || !strings.Contains(logStr, `"mode":"-rw-rw-rw-"`) {
+		t.Errorf("Expected a warning about the loose file: %s", logStr)
+	}
+}
+
+func TestFileModeFlagsLooseBackups(t *testing.T) {
+	tmpDir := t.TempDir()
+	backup := filepath.Join(tmpDir, "app-2026-01-02T15-04-05.000.log")
+	if err := os.WriteFile(backup, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chmod(backup, 0644)
+
+	logger := New(Config{LogDir: tmpDir, Filename: "app.log", FileMode: 0640})
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "app.log"))
+	if !strings.Contains(logStr, `"file":"app-2026-01-02T15-04-05.000.log"`) {
+		t.Errorf("Expected the loose backup to be flagged: %s", logStr)
+	}
+	if strings.Count(logStr, "looser permissions") != 1 {
+		t.Errorf("Expected only the backup to be flagged: %s", logStr)
+	}
+}
+
+func TestFileGroup(t *testing.T) {
+	tmpDir := t.TempDir()
+	gid := os.Getgid()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "group.log", FileGroup: strconv.Itoa(gid)})
+	logger.Info().Msg("group")
+	logger.fileWriter.(*rotatingWriter).Rotate()
+	logger.Close()
+
+	entries, _ := os.ReadDir(tmpDir)
+	for _, e := range entries {
+		if perm, group := fileInfo(t, filepath.Join(tmpDir, e.Name())); group != gid || perm != defaultFileMode {
+			t.Errorf("Expected %s to have group %d and mode %o, got %d and %o", e.Name(), gid, defaultFileMode, group, perm)
+		}
+	}
+}
+
+func TestUnknownFileGroupIsReported(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "nogroup.log", FileGroup: "no-such-group-for-logger-tests"})
+	logger.Info().Msg("still logging")
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "nogroup.log"))
+	if !strings.Contains(logStr, "Failed to set log file group") || !strings.Contains(logStr, "still logging") {
+		t.Errorf("Expected the group error to be logged and logging to continue: %s", logStr)
+	}
+}
+
+func TestAuditFileAndDirModes(t *testing.T) {
+	tmpDir := t.TempDir()
+	auditDir := filepath.Join(tmpDir, "audit")
+
+	logger := New(Config{LogDir: tmpDir, Audit: AuditConfig{Filename: "audit.log", Dir: auditDir, FileMode: 0640}})
+	logger.Close()
+
+	if perm, _ := fileInfo(t, auditDir); perm != 0700 {
+		t.Errorf("Expected audit directory mode 0700, got %o", perm)
+	}
+	if perm, _ := fileInfo(t, filepath.Join(auditDir, "audit.log")); perm != 0640 {
+		t.Errorf("Expected audit file mode 0640, got %o", perm)
+	}
+}
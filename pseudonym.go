@@ -0,0 +1,195 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// minPseudonymKeyLen is the minimum HMAC key length in bytes
+const minPseudonymKeyLen = 16
+
+// pseudonymTokenLen is the number of HMAC bytes kept in a token
+const pseudonymTokenLen = 16
+
+var (
+	// pseudonymPattern matches tokens of the form "<key id>:<22 base64url chars>"
+	pseudonymPattern = regexp.MustCompile(`([A-Za-z0-9_.\-]+):([A-Za-z0-9_\-]{22})`)
+
+	// pseudonymKeyIDPattern restricts key IDs to characters tokens can carry
+	pseudonymKeyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+)
+
+// ErrPseudonymKey is returned when the active pseudonymization key is missing or too short
+var ErrPseudonymKey = errors.New("pseudonym key missing or shorter than 16 bytes")
+
+// PseudonymConfig configures keyed pseudonymization of identifiers. Values
+// are replaced with "<key id>:<token>", where the token is a truncated
+// HMAC-SHA256 of the value, so the same identifier maps to the same token
+// under one key without the raw value being stored.
+type PseudonymConfig struct {
+	KeyID  string            // ID of the key used for new tokens
+	Keys   map[string][]byte // HMAC keys by ID; keep retired keys for re-identification
+	Fields []string          // Field names pseudonymized in every entry (case-insensitive)
+}
+
+// enabled reports whether pseudonymization is configured
+func (c PseudonymConfig) enabled() bool {
+	return c.KeyID != "" || len(c.Keys) > 0 || len(c.Fields) > 0
+}
+
+// Pseudonymizer computes and reverses keyed pseudonyms
+type Pseudonymizer struct {
+	keyID string
+	keys  map[string][]byte
+}
+
+// NewPseudonymizer returns a Pseudonymizer that creates tokens with the key
+// named keyID. Every key must be at least 16 bytes long.
+func NewPseudonymizer(keyID string, keys map[string][]byte) (*Pseudonymizer, error) {
+	if len(keys[keyID]) < minPseudonymKeyLen {
+		return nil, ErrPseudonymKey
+	}
+	p := &Pseudonymizer{keyID: keyID, keys: make(map[string][]byte, len(keys))}
+	for id, key := range keys {
+		if len(key) < minPseudonymKeyLen {
+			return nil, fmt.Errorf("%w: %s", ErrPseudonymKey, id)
+		}
+		if !pseudonymKeyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("invalid pseudonym key id %q", id)
+		}
+		p.keys[id] = append([]byte(nil), key...)
+	}
+	return p, nil
+}
+
+// Token returns the pseudonym of value under the active key
+func (p *Pseudonymizer) Token(value string) string {
+	return pseudonymToken(p.keyID, p.keys[p.keyID], value)
+}
+
+// Reidentify finds the candidate whose pseudonym equals token, using the key
+// named in the token. HMAC pseudonyms cannot be inverted, so re-identification
+// works by recomputing tokens for a list of known identifiers.
+func (p *Pseudonymizer) Reidentify(token string, candidates []string) (string, bool) {
+	keyID, _, ok := strings.Cut(token, ":")
+	if !ok {
+		return "", false
+	}
+	key, ok := p.keys[keyID]
+	if !ok {
+		return "", false
+	}
+	for _, c := range candidates {
+		if hmac.Equal([]byte(pseudonymToken(keyID, key, c)), []byte(token)) {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// FindPseudonyms returns the pseudonym tokens in s that use one of p's keys
+func (p *Pseudonymizer) FindPseudonyms(s string) []string {
+	var tokens []string
+	for _, m := range pseudonymPattern.FindAllStringSubmatch(s, -1) {
+		if _, ok := p.keys[m[1]]; ok {
+			tokens = append(tokens, m[0])
+		}
+	}
+	return tokens
+}
+
+// isToken reports whether s is already a pseudonym produced with one of p's keys
+func (p *Pseudonymizer) isToken(s string) bool {
+	m := pseudonymPattern.FindStringSubmatch(s)
+	if m == nil || m[0] != s {
+		return false
+	}
+	_, ok := p.keys[m[1]]
+	return ok
+}
+
+// pseudonymToken computes "<keyID>:<base64url(HMAC-SHA256(key, value)[:16])>"
+func pseudonymToken(keyID string, key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return keyID + ":" + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:pseudonymTokenLen])
+}
+
+// WithPseudonymField adds a field whose value is replaced with a keyed
+// pseudonym. If no valid key is configured the value is masked instead, so
+// the raw identifier is never written.
+func (l *Logger) WithPseudonymField(key string, value interface{}) *Logger {
+	text := fmt.Sprint(value)
+	if l.pseudonymizer == nil {
+		return l.derive(l.Logger.With().Str(key, RedactedPlaceholder).Logger())
+	}
+	return l.derive(l.Logger.With().Str(key, l.pseudonymizer.Token(text)).Logger())
+}
+
+// pseudonymFieldProcessor pseudonymizes the configured fields at any depth
+type pseudonymFieldProcessor struct {
+	p      *Pseudonymizer // nil when no valid key is configured
+	fields map[string]bool
+}
+
+// newPseudonymFieldProcessor prepares the configured field names for matching
+func newPseudonymFieldProcessor(p *Pseudonymizer, fields []string) *pseudonymFieldProcessor {
+	proc := &pseudonymFieldProcessor{p: p, fields: make(map[string]bool, len(fields))}
+	for _, f := range fields {
+		proc.fields[strings.ToLower(f)] = true
+	}
+	return proc
+}
+
+// process implements entryProcessor
+func (proc *pseudonymFieldProcessor) process(e *jsonObject) bool {
+	*e = proc.object(*e)
+	return true
+}
+
+// object rewrites configured fields of o and recurses into nested values
+func (proc *pseudonymFieldProcessor) object(o jsonObject) jsonObject {
+	for i, f := range o {
+		if proc.fields[strings.ToLower(f.Key)] {
+			o[i].Value = proc.token(f.Value)
+			continue
+		}
+		o[i].Value = proc.value(f.Value)
+	}
+	return o
+}
+
+// value recurses into objects and arrays
+func (proc *pseudonymFieldProcessor) value(v interface{}) interface{} {
+	switch val := v.(type) {
+	case jsonObject:
+		return proc.object(val)
+	case []interface{}:
+		for i := range val {
+			val[i] = proc.value(val[i])
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// token pseudonymizes a field value, leaving existing tokens and nulls alone
+func (proc *pseudonymFieldProcessor) token(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	if proc.p == nil {
+		return RedactedPlaceholder
+	}
+	text := valueString(v)
+	if proc.p.isToken(text) {
+		return text
+	}
+	return proc.p.Token(text)
+}
@@ -0,0 +1,201 @@
+package logger
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	testPseudonymKeyV1 = []byte("0123456789abcdef0123456789abcdef")
+	testPseudonymKeyV2 = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func TestPseudonymTokenStable(t *testing.T) {
+	p, err := NewPseudonymizer("v1", map[string][]byte{"v1": testPseudonymKeyV1})
+	if err != nil {
+		t.Fatalf("NewPseudonymizer returned error: %v", err)
+	}
+
+	first := p.Token("user-42")
+	if first != p.Token("user-42") {
+		t.Error("Expected the same value to map to the same token")
+	}
+	if first == p.Token("user-43") {
+		t.Error("Expected different values to map to different tokens")
+	}
+	if !strings.HasPrefix(first, "v1:") || len(first) != len("v1:")+22 {
+		t.Errorf("Unexpected token format %q", first)
+	}
+	if strings.Contains(first, "user-42") {
+		t.Errorf("Token leaks the raw value: %q", first)
+	}
+}
+
+func TestPseudonymKeyRotation(t *testing.T) {
+	old, _ := NewPseudonymizer("v1", map[string][]byte{"v1": testPseudonymKeyV1})
+	oldToken := old.Token("alice")
+
+	rotated, err := NewPseudonymizer("v2", map[string][]byte{"v1": testPseudonymKeyV1, "v2": testPseudonymKeyV2})
+	if err != nil {
+		t.Fatalf("NewPseudonymizer returned error: %v", err)
+	}
+	newToken := rotated.Token("alice")
+	if !strings.HasPrefix(newToken, "v2:") || newToken == oldToken {
+		t.Errorf("Expected a new token under key v2, got %q", newToken)
+	}
+
+	candidates := []string{"bob", "alice", "carol"}
+	for _, token := range []string{oldToken, newToken} {
+		got, ok := rotated.Reidentify(token, candidates)
+		if !ok || got != "alice" {
+			t.Errorf("Reidentify(%q) = (%q, %v), want alice", token, got, ok)
+		}
+	}
+	if _, ok := rotated.Reidentify(newToken, []string{"bob"}); ok {
+		t.Error("Expected no match without the right candidate")
+	}
+	if _, ok := old.Reidentify(newToken, candidates); ok {
+		t.Error("Expected tokens from an unknown key ID not to match")
+	}
+}
+
+func TestNewPseudonymizerErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		keyID string
+		keys  map[string][]byte
+	}{
+		{"missing active key", "v1", map[string][]byte{}},
+		{"short active key", "v1", map[string][]byte{"v1": []byte("short")}},
+		{"short retired key", "v2", map[string][]byte{"v1": []byte("short"), "v2": testPseudonymKeyV2}},
+		{"invalid key id", "v 1", map[string][]byte{"v 1": testPseudonymKeyV1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPseudonymizer(tt.keyID, tt.keys); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+	if _, err := NewPseudonymizer("v1", nil); !errors.Is(err, ErrPseudonymKey) {
+		t.Errorf("Expected ErrPseudonymKey, got %v", err)
+	}
+}
+
+func TestFindPseudonyms(t *testing.T) {
+	p, _ := NewPseudonymizer("v1", map[string][]byte{"v1": testPseudonymKeyV1})
+	a, b := p.Token("a"), p.Token("b")
+	line := `{"user":"` + a + `","peer":"` + b + `","other":"v9:AAAAAAAAAAAAAAAAAAAAAA"}`
+
+	got := p.FindPseudonyms(line)
+	if len(got) != 2 || got[0] != a || got[1] != b {
+		t.Errorf("FindPseudonyms = %v, want [%s %s]", got, a, b)
+	}
+}
+
+func TestWithPseudonymField(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{
+		LogDir:   tmpDir,
+		Filename: "pseudonym.log",
+		Pseudonym: PseudonymConfig{
+			KeyID: "v1",
+			Keys:  map[string][]byte{"v1": testPseudonymKeyV1},
+		},
+	})
+	logger.WithPseudonymField("user_id", 1234).Info().Msg("first")
+	logger.WithPseudonymField("user_id", "1234").Info().Msg("second")
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "pseudonym.log"))
+	if strings.Contains(logStr, "1234") {
+		t.Errorf("Raw user ID leaked: %s", logStr)
+	}
+
+	p, _ := NewPseudonymizer("v1", map[string][]byte{"v1": testPseudonymKeyV1})
+	token := p.Token("1234")
+	if strings.Count(logStr, `"user_id":"`+token+`"`) != 2 {
+		t.Errorf("Expected both entries to carry token %s: %s", token, logStr)
+	}
+}
+
+func TestWithPseudonymFieldWithoutKey(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "nokey.log"})
+	logger.WithPseudonymField("user_id", "alice").Info().Msg("masked")
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "nokey.log"))
+	if strings.Contains(logStr, "alice") || !strings.Contains(logStr, `"user_id":"[REDACTED]"`) {
+		t.Errorf("Expected value to be masked without a key: %s", logStr)
+	}
+}
+
+func TestPseudonymFields(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{
+		LogDir:   tmpDir,
+		Filename: "fields.log",
+		Pseudonym: PseudonymConfig{
+			KeyID:  "v1",
+			Keys:   map[string][]byte{"v1": testPseudonymKeyV1},
+			Fields: []string{"user_id", "Email"},
+		},
+	})
+	logger.
+		WithFields(map[string]interface{}{
+			"user_id": "u-77",
+			"request": map[string]interface{}{"email": "dana@example.com", "path": "/home"},
+		}).
+		WithPseudonymField("actor", "u-77").
+		Info().
+		Msg("nested")
+	logger.WithPseudonymField("user_id", "u-77").Info().Msg("already pseudonymized")
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "fields.log"))
+	if strings.Contains(logStr, "u-77") || strings.Contains(logStr, "dana@example.com") {
+		t.Errorf("Raw identifiers leaked: %s", logStr)
+	}
+	if !strings.Contains(logStr, `"path":"/home"`) {
+		t.Errorf("Expected unrelated fields to be kept: %s", logStr)
+	}
+
+	p, _ := NewPseudonymizer("v1", map[string][]byte{"v1": testPseudonymKeyV1})
+	token := p.Token("u-77")
+	if strings.Count(logStr, token) != 3 {
+		t.Errorf("Expected token %s once per occurrence without double hashing: %s", token, logStr)
+	}
+}
+
+func TestInvalidPseudonymConfigMasks(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{
+		LogDir:    tmpDir,
+		Filename:  "invalid.log",
+		Pseudonym: PseudonymConfig{KeyID: "v1", Keys: map[string][]byte{"v1": []byte("short")}, Fields: []string{"user"}},
+	})
+	logger.WithField("user", "erin").Info().Msg("masked")
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "invalid.log"))
+	if strings.Contains(logStr, "erin") {
+		t.Errorf("Expected value to be masked with an invalid key: %s", logStr)
+	}
+	if !strings.Contains(logStr, "Invalid pseudonym configuration") {
+		t.Errorf("Expected configuration error to be logged: %s", logStr)
+	}
+}
+
+func BenchmarkPseudonymToken(b *testing.B) {
+	p, _ := NewPseudonymizer("v1", map[string][]byte{"v1": testPseudonymKeyV1})
+	for i := 0; i < b.N; i++ {
+		_ = p.Token("user-42")
+	}
+}
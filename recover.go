@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+
+	"github.com/rs/zerolog"
+)
+
+// crashFileMode is the permission used when creating the crash file
+const crashFileMode os.FileMode = 0600
+
+// Recover logs a panic in progress, including its stack, at error level and
+// syncs the log file so the entry is flushed. The panic is swallowed and the
+// logger stays usable. It must be deferred directly:
+//
+//	go func() {
+//		defer log.Recover()
+//		work()
+//	}()
+func (l *Logger) Recover() {
+	if r := recover(); r != nil {
+		l.logPanic(zerolog.ErrorLevel, r)
+		_ = l.Sync()
+	}
+}
+
+// RecoverAndRepanic logs a panic in progress, including its stack, at fatal
+// level, closes the logger and re-panics with the original value. Like
+// Recover, it must be deferred directly.
+func (l *Logger) RecoverAndRepanic() {
+	if r := recover(); r != nil {
+		l.logPanic(zerolog.FatalLevel, r)
+		// The process is about to die, so release everything now
+		_ = l.Close()
+		panic(r)
+	}
+}
+
+// logPanic writes the panic value and the current stack. WithLevel is used
+// so that fatal level does not exit the process.
+func (l *Logger) logPanic(level zerolog.Level, r interface{}) {
+	event := l.WithLevel(level)
+	if err, ok := r.(error); ok {
+		event = event.Err(err)
+	}
+	event.
+		Str("panic", fmt.Sprint(r)).
+		Str("stack", string(debug.Stack())).
+		Msg("Recovered from panic")
+}
+
+// setCrashOutput directs the runtime's fatal error output (for example an
+// unrecovered panic in any goroutine) to the file name in dir, opened through
+// root when the logger is confined. The runtime keeps its own duplicate of
+// the descriptor, so the file is closed on return.
+func setCrashOutput(root *os.Root, dir, name string) error {
+	var f *os.File
+	var err error
+	if root != nil {
+		f, err = root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, crashFileMode)
+	} else {
+		f, err = os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_APPEND, crashFileMode)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return debug.SetCrashOutput(f, debug.CrashOptions{})
+}
@@ -0,0 +1,191 @@
+package logger
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "recover.log"})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer logger.Recover()
+		panic("worker exploded")
+	}()
+	<-done
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "recover.log"))
+	for _, want := range []string{
+		`"level":"error"`,
+		`"panic":"worker exploded"`,
+		`"stack":"goroutine `,
+		"Recovered from panic",
+	} {
+		if !strings.Contains(logStr, want) {
+			t.Errorf("Expected %s in log output, got: %s", want, logStr)
+		}
+	}
+}
+
+func TestRecoverWithError(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "recover-err.log"})
+
+	func() {
+		defer logger.Recover()
+		panic(errors.New("typed failure"))
+	}()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "recover-err.log"))
+	if !strings.Contains(logStr, `"error":"typed failure"`) {
+		t.Errorf("Expected error field for error panic values, got: %s", logStr)
+	}
+}
+
+func TestRecoverKeepsLogging(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := Config{LogDir: tmpDir, Filename: "recover-keep.log", FileMode: 0600}
+
+	logger := New(cfg)
+	defer logger.Close()
+	child := logger.WithField("worker", 1)
+
+	func() {
+		defer child.Recover()
+		panic("worker exploded")
+	}()
+	child.Info().Msg("after recover")
+	logger.Info().Msg("parent after recover")
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "recover-keep.log"))
+	for _, msg := range []string{"Recovered from panic", "after recover", "parent after recover"} {
+		if !strings.Contains(logStr, `"message":"`+msg+`"`) {
+			t.Errorf("Expected %q in log output, got: %s", msg, logStr)
+		}
+	}
+
+	// The child still holds its reference to the shared file
+	other := New(cfg)
+	defer other.Close()
+	if other.fileWriter != logger.fileWriter {
+		t.Error("Expected Recover to keep the log file open")
+	}
+}
+
+func TestRecoverNoPanic(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "nopanic.log"})
+
+	func() {
+		defer logger.Recover()
+	}()
+	logger.Close()
+
+	if strings.Contains(readLogFileIfExists(filepath.Join(tmpDir, "nopanic.log")), "panic") {
+		t.Error("Expected nothing to be logged without a panic")
+	}
+}
+
+func TestRecoverAndRepanic(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "repanic.log"})
+
+	var repanicked interface{}
+	func() {
+		defer func() { repanicked = recover() }()
+		defer logger.RecoverAndRepanic()
+		panic("fatal problem")
+	}()
+
+	if repanicked != "fatal problem" {
+		t.Errorf("Expected original panic value to be re-raised, got %v", repanicked)
+	}
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "repanic.log"))
+	if !strings.Contains(logStr, `"level":"fatal"`) || !strings.Contains(logStr, `"panic":"fatal problem"`) {
+		t.Errorf("Expected fatal entry for re-panicked value, got: %s", logStr)
+	}
+}
+
+func TestCrashFileInvalidName(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	for _, name := range []string{"../crash.log", "sub/crash.log", `..\crash.log`} {
+		logger := New(Config{LogDir: tmpDir, CrashFile: name})
+		if logger.fileWriter != nil {
+			t.Errorf("Expected stderr fallback for crash file %q", name)
+		}
+	}
+}
+
+func TestCrashFileCreated(t *testing.T) {
+	tmpDir := t.TempDir()
+	defer debug.SetCrashOutput(nil, debug.CrashOptions{})
+
+	logDir := filepath.Join(tmpDir, "logs")
+	logger := New(Config{LogDir: logDir, CrashFile: "crash.log", DirMode: 0700})
+	defer logger.Close()
+
+	info, err := os.Stat(filepath.Join(logDir, "crash.log"))
+	if err != nil {
+		t.Fatalf("Expected crash file to be created: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != crashFileMode {
+		t.Errorf("Expected crash file permissions %o, got %o", crashFileMode, perm)
+	}
+	dirInfo, err := os.Stat(logDir)
+	if err != nil {
+		t.Fatalf("Failed to stat log dir: %v", err)
+	}
+	if perm := dirInfo.Mode().Perm(); perm != 0700 {
+		t.Errorf("Expected log dir permissions 0700, got %o", perm)
+	}
+}
+
+// TestCrashOutputHelper crashes the process when run as a subprocess of
+// TestCrashOutputCapturesUnrecoveredPanic
+func TestCrashOutputHelper(t *testing.T) {
+	logDir := os.Getenv("GO_LOGGER_CRASH_DIR")
+	if logDir == "" {
+		t.Skip("helper process only")
+	}
+
+	New(Config{LogDir: logDir, CrashFile: "crash.log"})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		panic("unrecovered crash for test")
+	}()
+	<-done
+}
+
+func TestCrashOutputCapturesUnrecoveredPanic(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCrashOutputHelper$")
+	cmd.Env = append(os.Environ(), "GO_LOGGER_CRASH_DIR="+tmpDir)
+	if err := cmd.Run(); err == nil {
+		t.Fatal("Expected helper process to crash")
+	}
+
+	crash := readLogFile(t, filepath.Join(tmpDir, "crash.log"))
+	if !strings.Contains(crash, "panic: unrecovered crash for test") {
+		t.Errorf("Expected panic output in crash file, got: %s", crash)
+	}
+	if !strings.Contains(crash, "goroutine ") {
+		t.Error("Expected goroutine stack in crash file")
+	}
+}
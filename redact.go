@@ -0,0 +1,282 @@
+package logger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/netip"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+)
+
+// RedactAction selects how a sensitive value is rewritten
+type RedactAction int
+
+const (
+	// RedactMask replaces the value with RedactedPlaceholder
+	RedactMask RedactAction = iota
+	// RedactHash replaces the value with a short SHA-256 digest so equal
+	// values can still be correlated
+	RedactHash
+	// RedactDrop removes the field, or removes the match from text
+	RedactDrop
+	// RedactTruncate keeps only the first few characters of the value
+	RedactTruncate
+)
+
+// RedactedPlaceholder is the replacement written by RedactMask
+const RedactedPlaceholder = "[REDACTED]"
+
+// defaultTruncateKeep is the number of characters kept by RedactTruncate
+const defaultTruncateKeep = 4
+
+// RedactRule redacts fields by name, at any nesting depth
+type RedactRule struct {
+	Key    string       // Case-insensitive field name or glob pattern, e.g. "*token*"
+	Action RedactAction // How the value is rewritten (default: RedactMask)
+	Keep   int          // Characters kept by RedactTruncate (default: 4)
+}
+
+// RedactDetector finds sensitive substrings in string values and messages
+type RedactDetector struct {
+	Name     string                  // Detector name, for documentation and debugging
+	Pattern  *regexp.Regexp          // Candidate matches
+	Validate func(match string) bool // Optional check that filters false positives
+	Action   RedactAction            // How each match is rewritten (default: RedactMask)
+	Keep     int                     // Characters kept by RedactTruncate (default: 4)
+}
+
+// RedactConfig configures the redaction pipeline. Key rules are checked
+// first; string values of fields that no rule matches, including the message,
+// are scanned by the detectors. Redaction applies to every entry before it
+// reaches the file or console.
+type RedactConfig struct {
+	Rules     []RedactRule
+	Detectors []RedactDetector
+}
+
+// enabled reports whether any rule or detector is configured
+func (c RedactConfig) enabled() bool {
+	return len(c.Rules) > 0 || len(c.Detectors) > 0
+}
+
+// DefaultRedactRules returns key rules for common credential field names
+func DefaultRedactRules() []RedactRule {
+	keys := []string{
+		"password", "passwd", "pwd", "secret", "*secret*",
+		"authorization", "cookie", "set-cookie", "*token*",
+		"api_key", "apikey", "x-api-key", "private_key",
+	}
+	rules := make([]RedactRule, 0, len(keys))
+	for _, k := range keys {
+		rules = append(rules, RedactRule{Key: k, Action: RedactMask})
+	}
+	return rules
+}
+
+// DefaultRedactDetectors returns detectors for emails, payment card numbers
+// (Luhn-checked), bearer tokens, JWTs, AWS access keys and IP addresses
+func DefaultRedactDetectors() []RedactDetector {
+	return []RedactDetector{
+		{
+			Name:    "email",
+			Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		},
+		{
+			Name:     "card",
+			Pattern:  regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`),
+			Validate: luhnValid,
+		},
+		{
+			Name:    "bearer",
+			Pattern: regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`),
+		},
+		{
+			Name:    "jwt",
+			Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]+\.eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`),
+		},
+		{
+			Name:    "aws_access_key",
+			Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA)[0-9A-Z]{16}\b`),
+		},
+		{
+			Name:    "aws_secret_key",
+			Pattern: regexp.MustCompile(`(?i)aws_?secret_?access_?key["'\s:=]+[A-Za-z0-9/+=]{40}`),
+		},
+		{
+			Name:     "ipv4",
+			Pattern:  regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`),
+			Validate: func(s string) bool { a, err := netip.ParseAddr(s); return err == nil && a.Is4() },
+		},
+		{
+			Name: "ipv6",
+			// Full or "::"-compressed addresses with a group on both sides of
+			// "::", so that text such as "std::vector" or "Foo::Bar" is kept
+			Pattern:  regexp.MustCompile(`(?i)\b(?:[0-9a-f]{1,4}(?::[0-9a-f]{1,4}){7}|[0-9a-f]{1,4}(?::[0-9a-f]{1,4}){0,5}::[0-9a-f]{1,4}(?::[0-9a-f]{1,4}){0,5})\b`),
+			Validate: func(s string) bool { a, err := netip.ParseAddr(s); return err == nil && a.Is6() },
+		},
+	}
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum
+func luhnValid(s string) bool {
+	var sum, n int
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// redactor applies a RedactConfig to decoded entries
+type redactor struct {
+	rules     []RedactRule
+	detectors []RedactDetector
+}
+
+// newRedactor prepares cfg for matching
+func newRedactor(cfg RedactConfig) *redactor {
+	r := &redactor{detectors: cfg.Detectors}
+	for _, rule := range cfg.Rules {
+		rule.Key = strings.ToLower(rule.Key)
+		r.rules = append(r.rules, rule)
+	}
+	return r
+}
+
+// redactSkipFields are written by the logger itself and never scanned
+var redactSkipFields = map[string]bool{
+	zerolog.LevelFieldName:     true,
+	zerolog.TimestampFieldName: true,
+	zerolog.CallerFieldName:    true,
+}
+
+// process implements entryProcessor
+func (r *redactor) process(e *jsonObject) bool {
+	out := (*e)[:0]
+	for _, f := range *e {
+		if redactSkipFields[f.Key] {
+			out = append(out, f)
+			continue
+		}
+		value, keep := r.field(f.Key, f.Value)
+		if keep {
+			out = append(out, jsonField{Key: f.Key, Value: value})
+		}
+	}
+	*e = out
+	return true
+}
+
+// field redacts the value of one field, reporting false if it is dropped
+func (r *redactor) field(key string, value interface{}) (interface{}, bool) {
+	if rule, ok := r.matchRule(key); ok {
+		if rule.Action == RedactDrop {
+			return nil, false
+		}
+		return applyRedactAction(rule.Action, rule.Keep, valueString(value)), true
+	}
+	return r.value(value), true
+}
+
+// value scans a value that no key rule matched
+func (r *redactor) value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return r.text(v)
+	case jsonObject:
+		out := make(jsonObject, 0, len(v))
+		for _, f := range v {
+			if value, keep := r.field(f.Key, f.Value); keep {
+				out = append(out, jsonField{Key: f.Key, Value: value})
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = r.value(item)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// text rewrites every detector match in s
+func (r *redactor) text(s string) string {
+	for _, d := range r.detectors {
+		if d.Pattern == nil {
+			continue
+		}
+		s = d.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if d.Validate != nil && !d.Validate(match) {
+				return match
+			}
+			if d.Action == RedactDrop {
+				return ""
+			}
+			return applyRedactAction(d.Action, d.Keep, match)
+		})
+	}
+	return s
+}
+
+// matchRule returns the first rule matching key
+func (r *redactor) matchRule(key string) (RedactRule, bool) {
+	lower := strings.ToLower(key)
+	for _, rule := range r.rules {
+		if rule.Key == lower {
+			return rule, true
+		}
+		if ok, _ := path.Match(rule.Key, lower); ok {
+			return rule, true
+		}
+	}
+	return RedactRule{}, false
+}
+
+// applyRedactAction rewrites s according to action
+func applyRedactAction(action RedactAction, keep int, s string) string {
+	switch action {
+	case RedactHash:
+		sum := sha256.Sum256([]byte(s))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	case RedactTruncate:
+		if keep <= 0 {
+			keep = defaultTruncateKeep
+		}
+		if utf8.RuneCountInString(s) <= keep {
+			return s
+		}
+		runes := []rune(s)
+		return string(runes[:keep]) + "…"
+	default:
+		return RedactedPlaceholder
+	}
+}
+
+// valueString returns the text form of a decoded value for hashing and
+// truncation; non-string values use their JSON encoding
+func valueString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return string(appendJSONValue(nil, value))
+}
@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrFileConflict is reported when a logger opens a file that another logger
+// in the process already writes with different settings. Differing rotation
+// or permissions are logged and the existing writer is shared; a differing
+// hash chain or encryption key makes New fall back to stderr instead.
+var ErrFileConflict = errors.New("log file already open with different settings")
+
+// fileSettings are the settings of a log file that every logger writing it
+// must agree on
+type fileSettings struct {
+	maxSizeMB  int
+	maxBackups int
+	maxAge     time.Duration
+	mode       os.FileMode // 0 for lumberjack
+	group      string
+	hashChain  bool
+	keyID      string // Encryption key ID, "" for plain text
+}
+
+// sharedFiles holds the log files open in the process by resolved path.
+// Loggers for the same path share one writer, so they neither interleave
+// partial writes nor rotate the file from under each other.
+var sharedFiles = struct {
+	sync.Mutex
+	m map[string]*sharedFile
+}{m: make(map[string]*sharedFile)}
+
+// sharedFile is a file writer shared by every logger writing one path
+type sharedFile struct {
+	path     string
+	w        io.WriteCloser
+	sync     syncer
+	settings fileSettings
+	refs     int // Guarded by sharedFiles
+}
+
+// fileHandle is one logger's reference to a shared file
+type fileHandle struct {
+	*sharedFile
+	once sync.Once
+}
+
+// acquireFile returns a reference to the writer of path. If no logger has
+// the file open, open creates the writer. reused reports whether an existing
+// writer was returned; its rotation and permissions may differ from the
+// caller's. A writer with a different hash chain or encryption key is never
+// shared, as entries would be written unchained or in plain text.
+func acquireFile(path string, open func() (io.WriteCloser, syncer, error), settings fileSettings) (h *fileHandle, reused bool, err error) {
+	sharedFiles.Lock()
+	defer sharedFiles.Unlock()
+
+	if f, ok := sharedFiles.m[path]; ok {
+		if f.settings.hashChain != settings.hashChain || f.settings.keyID != settings.keyID {
+			return nil, false, fmt.Errorf("%w: %s is open with hash chain %t and encryption key %q, not %t and %q",
+				ErrFileConflict, path, f.settings.hashChain, f.settings.keyID, settings.hashChain, settings.keyID)
+		}
+		f.refs++
+		return &fileHandle{sharedFile: f}, true, nil
+	}
+	w, s, err := open()
+	if err != nil {
+		return nil, false, err
+	}
+	f := &sharedFile{path: path, w: w, sync: s, settings: settings, refs: 1}
+	sharedFiles.m[path] = f
+	return &fileHandle{sharedFile: f}, false, nil
+}
+
+// conflict returns an error wrapping ErrFileConflict if settings differ from
+// those the file was opened with
+func (h *fileHandle) conflict(settings fileSettings) error {
+	if h.settings == settings {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is open with %+v, not %+v", ErrFileConflict, h.path, h.settings, settings)
+}
+
+// Write implements io.Writer
+func (h *fileHandle) Write(p []byte) (int, error) {
+	return h.w.Write(p)
+}
+
+// Sync implements syncer
+func (h *fileHandle) Sync() error {
+	return h.sync.Sync()
+}
+
+// Close drops the reference, closing the writer when it was the last one.
+// Further calls do nothing.
+func (h *fileHandle) Close() error {
+	var err error
+	h.once.Do(func() {
+		sharedFiles.Lock()
+		defer sharedFiles.Unlock()
+		if h.refs--; h.refs > 0 {
+			return
+		}
+		delete(sharedFiles.m, h.path)
+		err = h.w.Close()
+	})
+	return err
+}
+
+// sharedDir resolves a log directory, inside allowedRoot when it is set, to
+// identify its files in sharedFiles. Symlinks are followed so that aliases of
+// one directory match.
+func sharedDir(allowedRoot, dir string) string {
+	if allowedRoot != "" && !filepath.IsAbs(dir) {
+		dir = filepath.Join(allowedRoot, dir)
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	return dir
+}
@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// forgetSharedFiles drops the open files from the registry, as a process
+// restart would, without closing them
+func forgetSharedFiles() {
+	sharedFiles.Lock()
+	defer sharedFiles.Unlock()
+	clear(sharedFiles.m)
+}
+
+func TestSharedFileWriter(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "shared.log")
+	cfg := Config{LogDir: tmpDir, Filename: "shared.log", FileMode: 0600}
+
+	first := New(cfg)
+	second := New(cfg)
+	if first.fileWriter != second.fileWriter {
+		t.Fatal("Expected loggers for the same file to share its writer")
+	}
+	first.Info().Msg("from first")
+	second.Info().Msg("from second")
+
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatalf("Second Close returned error: %v", err)
+	}
+	// Closing first again must not release second's reference
+	second.Info().Msg("after first closed")
+	if err := second.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	logStr := readLogFile(t, logPath)
+	for _, msg := range []string{"from first", "from second", "after first closed"} {
+		if !strings.Contains(logStr, `"message":"`+msg+`"`) {
+			t.Errorf("Expected %q in log: %s", msg, logStr)
+		}
+	}
+	if n := strings.Count(logStr, "\n"); n != 3 {
+		t.Errorf("Expected 3 entries, got %d: %s", n, logStr)
+	}
+
+	// The file is released once the last logger closes
+	third := New(cfg)
+	defer third.Close()
+	if third.fileWriter == first.fileWriter {
+		t.Error("Expected a new writer after every logger closed")
+	}
+}
+
+func TestSharedFileAliases(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.Symlink(tmpDir, filepath.Join(tmpDir, "alias")); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+	logDir := filepath.Join(tmpDir, "logs")
+
+	l := New(Config{LogDir: logDir, Filename: "app.log"})
+	defer l.Close()
+	for _, cfg := range []Config{
+		{LogDir: logDir + "/."},
+		{LogDir: filepath.Join(tmpDir, "alias", "logs")},
+		{LogDir: "logs", AllowedRoot: tmpDir},
+	} {
+		cfg.Filename = "app.log"
+		other := New(cfg)
+		if other.fileWriter != l.fileWriter {
+			t.Errorf("Expected LogDir %s to share the writer of %s", cfg.LogDir, logDir)
+		}
+		other.Close()
+	}
+}
+
+func TestSharedFileConflict(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "app.log")
+
+	first := New(Config{LogDir: tmpDir, Filename: "app.log", MaxSizeMB: 10, MaxBackups: 5})
+	defer first.Close()
+	second := New(Config{LogDir: tmpDir, Filename: "app.log", MaxSizeMB: 20, MaxBackups: 5})
+	defer second.Close()
+	if first.fileWriter != second.fileWriter {
+		t.Fatal("Expected the conflicting logger to share the existing writer")
+	}
+
+	logStr := readLogFile(t, logPath)
+	if !strings.Contains(logStr, "Log file is already open with different settings") ||
+		!strings.Contains(logStr, ErrFileConflict.Error()) {
+		t.Errorf("Expected a conflict error in log: %s", logStr)
+	}
+
+	h := first.file
+	if err := h.conflict(fileSettings{maxSizeMB: 20, maxBackups: 5, maxAge: defaultMaxAge}); !errors.Is(err, ErrFileConflict) {
+		t.Errorf("Expected ErrFileConflict, got %v", err)
+	}
+	if err := h.conflict(fileSettings{maxSizeMB: 10, maxBackups: 5, maxAge: defaultMaxAge}); err != nil {
+		t.Errorf("Expected no conflict for equal settings, got %v", err)
+	}
+}
+
+func TestSharedFileRefusesChainOrKeyChange(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "app.log")
+	plain := New(Config{LogDir: tmpDir, Filename: "app.log", FileMode: 0600})
+	defer plain.Close()
+
+	for name, cfg := range map[string]Config{
+		"encryption": {Encryption: EncryptionConfig{KeyID: "k1", Keys: map[string][]byte{"k1": testEncKey1}}},
+		"hash chain": {HashChain: HashChainConfig{Enabled: true}},
+	} {
+		cfg.LogDir, cfg.Filename, cfg.FileMode = tmpDir, "app.log", 0600
+		other := New(cfg)
+		if other.fileWriter != nil {
+			t.Errorf("%s: expected a stderr fallback instead of sharing the plain writer", name)
+		}
+		other.Info().Str("ssn", "123-45-6789").Msg("sensitive")
+		other.Close()
+	}
+	if logStr := readLogFileIfExists(logPath); strings.Contains(logStr, "123-45-6789") {
+		t.Errorf("Expected no entries from the refused loggers: %s", logStr)
+	}
+
+	_, _, err := acquireFile(plain.file.path, nil, fileSettings{mode: 0600, maxAge: defaultMaxAge, keyID: "k1"})
+	if !errors.Is(err, ErrFileConflict) {
+		t.Errorf("Expected ErrFileConflict, got %v", err)
+	}
+
+	// The audit file is refused the same way, so its entries return the error
+	audited := New(Config{LogDir: tmpDir, Filename: "audited.log", Audit: AuditConfig{Filename: "app.log"}, HashChain: HashChainConfig{Enabled: true}})
+	defer audited.Close()
+	if !errors.Is(audited.audit.err, ErrFileConflict) {
+		t.Errorf("Expected the audit file to be refused with ErrFileConflict, got %v", audited.audit.err)
+	}
+}
+
+func TestSharedAuditAndRouteFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := Config{
+		LogDir: tmpDir,
+		Audit:  AuditConfig{Filename: "audit.log"},
+		Route:  RouteConfig{Field: "tenant"},
+	}
+
+	first := New(cfg)
+	second := New(cfg)
+	if first.audit.w.sharedFile != second.audit.w.sharedFile {
+		t.Error("Expected loggers for the same audit file to share its writer")
+	}
+	first.Info().Str("tenant", "acme").Msg("from first")
+	second.Info().Str("tenant", "acme").Msg("from second")
+	routed := func(l *Logger) *sharedFile {
+		rw := l.closers[0].(*routeWriter)
+		rw.mu.Lock()
+		defer rw.mu.Unlock()
+		return rw.files["acme"].Value.(*routeFile).w.sharedFile
+	}
+	if routed(first) != routed(second) {
+		t.Error("Expected loggers for the same routed file to share its writer")
+	}
+
+	for _, l := range []*Logger{first, second} {
+		if err := l.Audit().Actor("a").Action("b").Target("c").Outcome(AuditSuccess).Msg(""); err != nil {
+			t.Errorf("Audit returned error: %v", err)
+		}
+	}
+	first.Close()
+	second.Close()
+
+	if n := strings.Count(readLogFile(t, filepath.Join(tmpDir, "audit.log")), "\n"); n != 2 {
+		t.Errorf("Expected 2 audit entries, got %d", n)
+	}
+	if n := strings.Count(readLogFile(t, filepath.Join(tmpDir, "tenant", "acme", "acme.log")), "\n"); n != 2 {
+		t.Errorf("Expected 2 routed entries, got %d", n)
+	}
+}
+
+func TestSharedAuditFileConflict(t *testing.T) {
+	tmpDir := t.TempDir()
+	first := New(Config{LogDir: tmpDir, Filename: "app.log", Audit: AuditConfig{Filename: "audit.log", MaxSizeMB: 10}})
+	defer first.Close()
+	second := New(Config{LogDir: tmpDir, Filename: "app.log", Audit: AuditConfig{Filename: "audit.log", MaxSizeMB: 20}})
+	defer second.Close()
+	if first.audit.w.sharedFile != second.audit.w.sharedFile {
+		t.Fatal("Expected the conflicting logger to share the existing audit writer")
+	}
+	if !errors.Is(second.audit.conflict, ErrFileConflict) {
+		t.Errorf("Expected an audit conflict, got %v", second.audit.conflict)
+	}
+	if logStr := readLogFile(t, filepath.Join(tmpDir, "app.log")); !strings.Contains(logStr, "Audit file is already open with different settings") {
+		t.Errorf("Expected the audit conflict in log: %s", logStr)
+	}
+}
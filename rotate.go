@@ -0,0 +1,389 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// backupTimeFormat matches lumberjack's backup names, e.g.
+	// "go-2026-01-02T15-04-05.000.log", so existing tooling keeps working
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+
+	// defaultMaxAge matches the retention configured for lumberjack
+	defaultMaxAge = 30 * 24 * time.Hour
+
+	// defaultFileMode is the permission used when creating log files
+	defaultFileMode os.FileMode = 0600
+)
+
+// rotatingWriter is a size-based rotating file writer that performs every
+// file operation through an os.Root, so neither the active file nor its
+// backups can resolve outside the log directory, even if path components are
+// swapped for symlinks while the logger runs.
+type rotatingWriter struct {
+	root *os.Root
+	name string // File name inside root
+	opts rotateOptions
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	seg  *segment // Encryption state of the current file
+}
+
+// rotateOptions configures a rotatingWriter
+type rotateOptions struct {
+	maxSize    int64 // Bytes written before rotating
+	maxBackups int
+	maxAge     time.Duration
+	mode       os.FileMode     // Applied with fchmod, so the umask does not matter
+	gid        int             // Group owner of log files, -1 to leave unchanged
+	encrypt    *encryptionKeys // Encrypts each file under a fresh data key, nil for plain text
+	sync       bool            // fsync every write, and the directory when files are created
+	syncClose  bool            // fsync each file before it is closed, for Config.Sync policies
+
+	// header and footer return bytes written at the start of every new, empty
+	// file and just before a file is closed by rotation or Close. They are
+	// called with the writer's lock held.
+	header func() []byte
+	footer func() []byte
+}
+
+// newRotatingWriter opens or creates name inside root. The writer takes
+// ownership of root and closes it on Close.
+func newRotatingWriter(root *os.Root, name string, opts rotateOptions) (*rotatingWriter, error) {
+	w := &rotatingWriter{root: root, name: name, opts: opts}
+	if err := w.openExisting(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write implements io.Writer, rotating first if p would exceed the size limit
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	need := int64(len(p))
+	if w.opts.encrypt != nil {
+		need += chunkOverhead
+	}
+	if need > w.opts.maxSize {
+		return 0, fmt.Errorf("write length %d exceeds maximum file size %d", len(p), w.opts.maxSize)
+	}
+	if w.file == nil {
+		if err := w.openExisting(); err != nil {
+			return 0, err
+		}
+	}
+	if w.size+need > w.opts.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.write(p)
+	if err == nil && w.opts.sync {
+		err = w.file.Sync()
+	}
+	return n, err
+}
+
+// Rotate closes the current file, renames it to a timestamped backup and
+// starts a new one
+func (w *rotatingWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rotate()
+}
+
+// Sync flushes the current file to stable storage
+func (w *rotatingWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close closes the current file and the root
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var errs []error
+	if w.file != nil {
+		errs = append(errs, w.finish(), w.file.Close())
+		w.file = nil
+	}
+	errs = append(errs, w.root.Close())
+	return errors.Join(errs...)
+}
+
+// openExisting opens the log file for appending, creating it if needed, and
+// applies the configured permissions and group. An existing encrypted file
+// cannot be continued without its data key, so it is rotated instead.
+func (w *rotatingWriter) openExisting() error {
+	f, err := w.root.OpenFile(w.name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, w.opts.mode)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err == nil && !info.Mode().IsRegular() {
+		err = fmt.Errorf("log file %s is not a regular file", w.name)
+	}
+	if err == nil {
+		err = w.applyPermissions(f)
+	}
+	if err == nil && w.opts.encrypt != nil && info.Size() > 0 {
+		f.Close()
+		return w.rotate()
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.size = f, info.Size()
+	if w.size == 0 {
+		if err := w.syncDir(); err != nil {
+			return err
+		}
+		return w.start()
+	}
+	return nil
+}
+
+// syncDir makes file creation and renames durable when syncing is enabled
+func (w *rotatingWriter) syncDir() error {
+	if !w.opts.sync {
+		return nil
+	}
+	dir, err := w.root.Open(".")
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// start writes the encryption header and the header to a new, empty file.
+// On failure the file is closed, so nothing is ever written unencrypted.
+func (w *rotatingWriter) start() error {
+	err := func() error {
+		if w.opts.encrypt != nil {
+			seg, hdr, err := newSegment(w.opts.encrypt)
+			if err != nil {
+				return err
+			}
+			if _, err := w.writeRaw(hdr); err != nil {
+				return err
+			}
+			w.seg = seg
+		}
+		if w.opts.header != nil {
+			if _, err := w.write(w.opts.header()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		w.file.Close()
+		w.file, w.seg = nil, nil
+	}
+	return err
+}
+
+// finish writes the footer and the final encrypted chunk to the current
+// file before it is closed, syncing it when a sync policy is set
+func (w *rotatingWriter) finish() error {
+	var errs []error
+	if w.opts.footer != nil {
+		if b := w.opts.footer(); len(b) > 0 {
+			_, err := w.write(b)
+			errs = append(errs, err)
+		}
+	}
+	if w.seg != nil {
+		chunk, err := w.seg.seal(nil, true)
+		if err == nil {
+			_, err = w.writeRaw(chunk)
+		}
+		errs = append(errs, err)
+		w.seg = nil
+	}
+	// A file is never written again once finished, so a policy's pending
+	// entries must be flushed now
+	if w.opts.sync || w.opts.syncClose {
+		errs = append(errs, w.file.Sync())
+	}
+	return errors.Join(errs...)
+}
+
+// write writes p to the current file, sealed as one chunk when encrypting
+func (w *rotatingWriter) write(p []byte) (int, error) {
+	if w.seg == nil {
+		return w.writeRaw(p)
+	}
+	chunk, err := w.seg.seal(p, false)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.writeRaw(chunk); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeRaw writes b to the current file as is
+func (w *rotatingWriter) writeRaw(b []byte) (int, error) {
+	n, err := w.file.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// applyPermissions sets the file mode and group on an open file
+func (w *rotatingWriter) applyPermissions(f *os.File) error {
+	if err := f.Chmod(w.opts.mode); err != nil {
+		return err
+	}
+	if w.opts.gid >= 0 {
+		return f.Chown(-1, w.opts.gid)
+	}
+	return nil
+}
+
+// rotate must be called with w.mu held
+func (w *rotatingWriter) rotate() error {
+	if w.file != nil {
+		// A failed footer must not block rotation
+		_ = w.finish()
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+
+	// Rename never follows a symlink at the old name, it moves the link
+	if err := w.root.Rename(w.name, backupName(w.name, time.Now())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// O_EXCL refuses whatever may have been planted at the name since the rename
+	f, err := w.root.OpenFile(w.name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, w.opts.mode)
+	if err != nil {
+		return err
+	}
+	// Startup already verified that permissions can be applied; a failure
+	// here must not cost entries
+	_ = w.applyPermissions(f)
+	w.file, w.size = f, 0
+	if err := w.syncDir(); err != nil {
+		return err
+	}
+
+	w.removeOldBackups()
+	return w.start()
+}
+
+// removeOldBackups deletes backups beyond maxBackups or older than maxAge.
+// Errors are ignored, as lumberjack does, so cleanup never blocks logging.
+func (w *rotatingWriter) removeOldBackups() {
+	dir, err := w.root.Open(".")
+	if err != nil {
+		return
+	}
+	entries, err := dir.ReadDir(-1)
+	dir.Close()
+	if err != nil {
+		return
+	}
+
+	type backup struct {
+		name string
+		time time.Time
+	}
+	var backups []backup
+	for _, e := range entries {
+		if t, ok := backupTime(w.name, e.Name()); ok {
+			backups = append(backups, backup{name: e.Name(), time: t})
+		}
+	}
+	slices.SortFunc(backups, func(a, b backup) int { return b.time.Compare(a.time) })
+
+	cutoff := time.Now().Add(-w.opts.maxAge)
+	for i, b := range backups {
+		if (w.opts.maxBackups > 0 && i >= w.opts.maxBackups) || (w.opts.maxAge > 0 && b.time.Before(cutoff)) {
+			_ = w.root.Remove(b.name)
+		}
+	}
+}
+
+// backupName returns the backup name for name rotated at t, in UTC like
+// lumberjack: "app.log" becomes "app-2026-01-02T15-04-05.000.log"
+func backupName(name string, t time.Time) string {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-" + t.UTC().Format(backupTimeFormat) + ext
+}
+
+// backupTime parses the rotation time from a backup of name
+func backupTime(name, candidate string) (time.Time, bool) {
+	ext := filepath.Ext(name)
+	stamp, ok := strings.CutPrefix(candidate, strings.TrimSuffix(name, ext)+"-")
+	if !ok {
+		return time.Time{}, false
+	}
+	stamp, ok = strings.CutSuffix(stamp, ext)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(backupTimeFormat, stamp)
+	return t, err == nil
+}
+
+// openLogRoot opens logDir inside allowedRoot, creating it if needed.
+// Relative log directories are taken relative to allowedRoot; absolute ones
+// must lie within it.
+func openLogRoot(allowedRoot, logDir string, dirMode os.FileMode) (*os.Root, error) {
+	base, err := filepath.Abs(allowedRoot)
+	if err != nil {
+		return nil, err
+	}
+	rel := logDir
+	if filepath.IsAbs(logDir) {
+		rel, err = filepath.Rel(base, logDir)
+		if err != nil || hasParentRef(rel) {
+			return nil, fmt.Errorf("LogDir %s is outside AllowedRoot %s", logDir, base)
+		}
+	}
+
+	root, err := os.OpenRoot(base)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	if err := root.MkdirAll(rel, dirMode); err != nil {
+		return nil, err
+	}
+	return root.OpenRoot(rel)
+}
+
+// hasParentRef reports whether path contains a ".." element. Names that
+// merely contain dots, such as "logs..old", are allowed.
+func hasParentRef(path string) bool {
+	for _, elem := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if elem == ".." {
+			return true
+		}
+	}
+	return false
+}
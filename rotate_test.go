@@ -0,0 +1,284 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// confinedDirs returns an allowed root and a directory outside it holding a
+// sentinel file that must never change
+func confinedDirs(t *testing.T) (root, outside, sentinel string) {
+	t.Helper()
+	base := t.TempDir()
+	root = filepath.Join(base, "root")
+	outside = filepath.Join(base, "outside")
+	for _, dir := range []string{root, outside} {
+		if err := os.Mkdir(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sentinel = filepath.Join(outside, "secret")
+	if err := os.WriteFile(sentinel, []byte("untouched"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return root, outside, sentinel
+}
+
+// assertUntouched fails if anything was written outside the root
+func assertUntouched(t *testing.T, outside, sentinel string) {
+	t.Helper()
+	if data, _ := os.ReadFile(sentinel); string(data) != "untouched" {
+		t.Errorf("File outside the root was modified: %q", data)
+	}
+	entries, _ := os.ReadDir(outside)
+	if len(entries) != 1 {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("Files were created outside the root: %v", names)
+	}
+}
+
+// newTestRotatingWriter opens a rotating writer on dir with a small size limit
+func newTestRotatingWriter(t *testing.T, dir string, maxSize int64, maxBackups int) *rotatingWriter {
+	t.Helper()
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := newRotatingWriter(root, "app.log", rotateOptions{maxSize: maxSize, maxBackups: maxBackups, mode: defaultFileMode, gid: -1})
+	if err != nil {
+		t.Fatalf("newRotatingWriter returned error: %v", err)
+	}
+	return w
+}
+
+func TestHasParentRef(t *testing.T) {
+	tests := map[string]bool{
+		"../etc":        true,
+		"logs/../../x":  true,
+		`logs\..\x`:     true,
+		"..":            true,
+		"logs..old":     false,
+		"./logs/..a/b.": false,
+		"/var/log/app":  false,
+	}
+	for path, want := range tests {
+		if got := hasParentRef(path); got != want {
+			t.Errorf("hasParentRef(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestLogDirWithDoubleDotsInName(t *testing.T) {
+	logDir := filepath.Join(t.TempDir(), "logs..old")
+
+	logger := New(Config{LogDir: logDir, Filename: "app..log"})
+	logger.Info().Msg("dots in names are not traversal")
+	logger.Close()
+
+	readLogFile(t, filepath.Join(logDir, "app..log"))
+}
+
+func TestBackupNames(t *testing.T) {
+	at := time.Date(2026, 1, 2, 15, 4, 5, 123e6, time.UTC)
+	name := backupName("app.log", at)
+	if name != "app-2026-01-02T15-04-05.123.log" {
+		t.Errorf("Unexpected backup name %q", name)
+	}
+	if got, ok := backupTime("app.log", name); !ok || !got.Equal(at) {
+		t.Errorf("backupTime(%q) = %v, %v", name, got, ok)
+	}
+	for _, other := range []string{"app.log", "app-latest.log", "other-2026-01-02T15-04-05.123.log", "app-2026-01-02T15-04-05.123.txt"} {
+		if _, ok := backupTime("app.log", other); ok {
+			t.Errorf("Expected %q not to be a backup of app.log", other)
+		}
+	}
+}
+
+func TestRotatingWriterRotates(t *testing.T) {
+	dir := t.TempDir()
+	w := newTestRotatingWriter(t, dir, 64, 2)
+
+	line := []byte(strings.Repeat("x", 39) + "\n")
+	for i := 0; i < 5; i++ {
+		if _, err := w.Write(line); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+		time.Sleep(2 * time.Millisecond) // Distinct backup timestamps
+	}
+	if _, err := w.Write(make([]byte, 65)); err == nil {
+		t.Error("Expected an error for a write larger than the maximum size")
+	}
+	w.Close()
+
+	entries, _ := os.ReadDir(dir)
+	backups := 0
+	for _, e := range entries {
+		if _, ok := backupTime("app.log", e.Name()); ok {
+			backups++
+		}
+	}
+	if backups != 2 {
+		t.Errorf("Expected MaxBackups to keep 2 backups, found %d in %v", backups, entries)
+	}
+	if data := readLogFile(t, filepath.Join(dir, "app.log")); data != string(line) {
+		t.Errorf("Expected the active file to hold the last line, got %q", data)
+	}
+}
+
+func TestAllowedRootRelativeLogDir(t *testing.T) {
+	root, outside, sentinel := confinedDirs(t)
+
+	logger := New(Config{AllowedRoot: root, LogDir: "app/logs", Filename: "app.log"})
+	logger.Info().Msg("confined")
+	logger.Close()
+
+	if !strings.Contains(readLogFile(t, filepath.Join(root, "app", "logs", "app.log")), "confined") {
+		t.Error("Expected relative LogDir to be created inside AllowedRoot")
+	}
+	assertUntouched(t, outside, sentinel)
+}
+
+func TestAllowedRootRejectsOutsideLogDir(t *testing.T) {
+	root, outside, sentinel := confinedDirs(t)
+
+	logger := New(Config{AllowedRoot: root, LogDir: outside, Filename: "app.log"})
+	logger.Info().Msg("should go to stderr")
+	logger.Close()
+
+	assertUntouched(t, outside, sentinel)
+}
+
+func TestAllowedRootRejectsSymlinkedLogDir(t *testing.T) {
+	root, outside, sentinel := confinedDirs(t)
+	if err := os.Symlink(outside, filepath.Join(root, "logs")); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := New(Config{AllowedRoot: root, LogDir: "logs", Filename: "app.log"})
+	logger.Info().Msg("should go to stderr")
+	logger.Close()
+
+	assertUntouched(t, outside, sentinel)
+}
+
+func TestAllowedRootRejectsSymlinkedLogFile(t *testing.T) {
+	root, outside, sentinel := confinedDirs(t)
+	if err := os.Symlink(sentinel, filepath.Join(root, "app.log")); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := New(Config{AllowedRoot: root, LogDir: root, Filename: "app.log"})
+	logger.Info().Msg("should go to stderr")
+	logger.Close()
+
+	assertUntouched(t, outside, sentinel)
+}
+
+func TestAllowedRootRejectsSymlinkedCrashFile(t *testing.T) {
+	root, outside, sentinel := confinedDirs(t)
+	if err := os.Symlink(sentinel, filepath.Join(root, "crash.log")); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := New(Config{AllowedRoot: root, LogDir: root, Filename: "app.log", CrashFile: "crash.log"})
+	logger.Close()
+
+	if !strings.Contains(readLogFile(t, filepath.Join(root, "app.log")), "Failed to set crash output") {
+		t.Error("Expected the crash file escape to be reported")
+	}
+	assertUntouched(t, outside, sentinel)
+}
+
+func TestRotationAfterFileSwappedForSymlink(t *testing.T) {
+	root, outside, sentinel := confinedDirs(t)
+	w := newTestRotatingWriter(t, root, 1024, 5)
+	defer w.Close()
+
+	// Swap the active file for a link to a file outside the root
+	active := filepath.Join(root, "app.log")
+	os.Remove(active)
+	if err := os.Symlink(sentinel, active); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Rotate(); err != nil {
+		t.Fatalf("Rotate returned error: %v", err)
+	}
+	if _, err := w.Write([]byte("after swap\n")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	info, err := os.Lstat(active)
+	if err != nil || !info.Mode().IsRegular() {
+		t.Errorf("Expected a fresh regular file after rotation, got %v, %v", info, err)
+	}
+	assertUntouched(t, outside, sentinel)
+}
+
+func TestLogDirSwappedAfterOpen(t *testing.T) {
+	root, outside, sentinel := confinedDirs(t)
+	logDir := filepath.Join(root, "logs")
+
+	logger := New(Config{AllowedRoot: root, LogDir: "logs", Filename: "app.log"})
+	logger.Info().Msg("before swap")
+
+	// Move the directory away and put a link to the outside in its place
+	if err := os.Rename(logDir, filepath.Join(root, "moved")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, logDir); err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info().Msg("after swap")
+	logger.fileWriter.(*rotatingWriter).Rotate()
+	logger.Info().Msg("after rotation")
+	logger.Close()
+
+	if !strings.Contains(readLogFile(t, filepath.Join(root, "moved", "app.log")), "after rotation") {
+		t.Error("Expected writes to follow the directory that was opened")
+	}
+	assertUntouched(t, outside, sentinel)
+}
+
+func TestRotationSymlinkSwapRace(t *testing.T) {
+	root, outside, sentinel := confinedDirs(t)
+	w := newTestRotatingWriter(t, root, 256, 3)
+	defer w.Close()
+
+	active := filepath.Join(root, "app.log")
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			// Keep replacing the active file with links out of the root
+			os.Remove(active)
+			os.Symlink(sentinel, active)
+			os.Symlink(outside, active+".tmp")
+			os.Rename(active+".tmp", active)
+		}
+	}()
+
+	line := []byte(strings.Repeat("y", 63) + "\n")
+	for i := 0; i < 2000; i++ {
+		_, _ = w.Write(line) // Failures are expected while the attacker wins a race
+	}
+	close(stop)
+	wg.Wait()
+
+	assertUntouched(t, outside, sentinel)
+}
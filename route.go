@@ -0,0 +1,270 @@
+package logger
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultRouteMaxOpen is the number of routed files kept open by default
+	defaultRouteMaxOpen = 64
+
+	// defaultRouteIdleTimeout is how long an unused routed file stays open by default
+	defaultRouteIdleTimeout = 5 * time.Minute
+
+	// routeFileExt is appended to a field value to name its file
+	routeFileExt = ".log"
+
+	// maxRouteValue is the longest routed value in bytes, leaving room for the
+	// extension and backup timestamp within file name limits
+	maxRouteValue = 200
+)
+
+// RouteConfig writes entries to a file chosen by a field value, such as one
+// file per tenant, instead of the main log file. Each value gets its own
+// directory, so one value's backups can never be mistaken for another's
+// file. Routed files rotate like the main file and share its size, backup,
+// permission, encryption, hash chain and format settings. Entries without
+// the field, whose value is not a valid file name or is longer than 200
+// bytes, or whose file cannot be opened, go to the main log file.
+type RouteConfig struct {
+	Field       string        // Field whose value names the file, e.g. "tenant_id"; empty disables routing
+	Dir         string        // Subdirectory of LogDir holding the "<value>/<value>.log" files (default: Field)
+	MaxOpen     int           // Routed files open at once, least recently used closed first (default: 64)
+	IdleTimeout time.Duration // Routed files unused this long are closed (default: 5m)
+}
+
+// routeOptions holds the settings routed files share with the main file
+type routeOptions struct {
+	root     *os.Root // LogDir when confined, nil to use logDir
+	logDir   string
+	path     string // Resolved LogDir, identifying routed files in sharedFiles
+	sync     syncer // Syncs the main log file
+	dirMode  os.FileMode
+	text     bool                                                     // Write routed files in FormatText
+	settings fileSettings                                             // Compared by sharedFiles
+	open     func(root *os.Root, name string) (io.WriteCloser, error) // Takes ownership of root
+}
+
+// routeWriter sends each entry to the file named by its routing field
+type routeWriter struct {
+	main   io.Writer
+	dir    *os.Root // Directory of the routed files
+	path   string   // Resolved path of dir
+	field  string
+	marker []byte // `"<field>":`, to skip decoding entries without the field
+	opts   routeOptions
+	max    int
+	idle   time.Duration
+
+	mu    sync.Mutex
+	files map[string]*list.Element
+	order *list.List // *routeFile, most recently used first
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// routeFile is one open routed file
+type routeFile struct {
+	value string
+	out   io.Writer   // Writes entries to w, formatted as text for FormatText
+	w     *fileHandle // The file's rotating or chain writer, shared with other loggers
+	used  time.Time
+}
+
+// newRouteWriter creates the routing directory, in opts.root when it is set
+// or in opts.logDir otherwise, and starts the loop that closes idle files.
+// Entries that are not routed go to main.
+func newRouteWriter(main io.Writer, cfg RouteConfig, opts routeOptions) (*routeWriter, error) {
+	dir := cfg.Dir
+	if dir == "" {
+		dir = cfg.Field
+	}
+	dir = filepath.Clean(dir)
+	if filepath.IsAbs(dir) || hasParentRef(dir) {
+		return nil, errors.New("route Dir must be a relative path inside LogDir: " + cfg.Dir)
+	}
+
+	var dirRoot *os.Root
+	var err error
+	if opts.root != nil {
+		if err = opts.root.MkdirAll(dir, opts.dirMode); err == nil {
+			dirRoot, err = opts.root.OpenRoot(dir)
+		}
+	} else if err = os.MkdirAll(filepath.Join(opts.logDir, dir), opts.dirMode); err == nil {
+		dirRoot, err = os.OpenRoot(filepath.Join(opts.logDir, dir))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	marker, _ := json.Marshal(cfg.Field)
+	w := &routeWriter{
+		main:   main,
+		dir:    dirRoot,
+		path:   filepath.Join(opts.path, dir),
+		field:  cfg.Field,
+		marker: append(marker, ':'),
+		opts:   opts,
+		max:    cfg.MaxOpen,
+		idle:   cfg.IdleTimeout,
+		files:  make(map[string]*list.Element),
+		order:  list.New(),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if w.max <= 0 {
+		w.max = defaultRouteMaxOpen
+	}
+	if w.idle <= 0 {
+		w.idle = defaultRouteIdleTimeout
+	}
+	go w.loop()
+	return w, nil
+}
+
+// Write implements io.Writer
+func (w *routeWriter) Write(p []byte) (int, error) {
+	value, ok := w.route(p)
+	if !ok {
+		return w.main.Write(p)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	f, err := w.openLocked(value)
+	if err != nil {
+		return w.main.Write(p)
+	}
+	f.used = time.Now()
+	return f.out.Write(p)
+}
+
+// Sync flushes the main log file and every open routed file
+func (w *routeWriter) Sync() error {
+	errs := []error{w.opts.sync.Sync()}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for el := w.order.Front(); el != nil; el = el.Next() {
+		errs = append(errs, el.Value.(*routeFile).w.Sync())
+	}
+	return errors.Join(errs...)
+}
+
+// route returns the routing value of an entry, false if it is not routed
+func (w *routeWriter) route(p []byte) (string, bool) {
+	if !bytes.Contains(p, w.marker) {
+		return "", false
+	}
+	e, err := decodeEntry(p)
+	if err != nil {
+		return "", false
+	}
+	v, _ := e.get(w.field)
+	var value string
+	switch v := v.(type) {
+	case string:
+		value = v
+	case json.Number:
+		value = v.String()
+	default:
+		return "", false
+	}
+	if value == "" || len(value) > maxRouteValue || filepath.Clean(value) != value || !validFilename(value) {
+		return "", false
+	}
+	return value, true
+}
+
+// openLocked returns the open file of value, opening it in its own directory
+// and closing the least recently used file if needed. Loggers routing to the
+// same directory share the file's writer. It must be called with w.mu held.
+func (w *routeWriter) openLocked(value string) (*routeFile, error) {
+	if el, ok := w.files[value]; ok {
+		w.order.MoveToFront(el)
+		return el.Value.(*routeFile), nil
+	}
+	if w.order.Len() >= w.max {
+		_ = w.closeLocked(w.order.Back())
+	}
+
+	name := value + routeFileExt
+	h, _, err := acquireFile(filepath.Join(w.path, value, name), func() (io.WriteCloser, syncer, error) {
+		if err := w.dir.Mkdir(value, w.opts.dirMode); err != nil && !errors.Is(err, fs.ErrExist) {
+			return nil, nil, err
+		}
+		// Every file writer owns its root
+		root, err := w.dir.OpenRoot(value)
+		if err != nil {
+			return nil, nil, err
+		}
+		fw, err := w.opts.open(root, name)
+		if err != nil {
+			_ = root.Close()
+			return nil, nil, err
+		}
+		return fw, fw.(syncer), nil
+	}, w.opts.settings)
+	if err != nil {
+		return nil, err
+	}
+	f := &routeFile{value: value, out: h, w: h}
+	if w.opts.text {
+		f.out = newTextFileWriter(h)
+	}
+	w.files[value] = w.order.PushFront(f)
+	return f, nil
+}
+
+// closeLocked closes one routed file. It must be called with w.mu held.
+func (w *routeWriter) closeLocked(el *list.Element) error {
+	f := w.order.Remove(el).(*routeFile)
+	delete(w.files, f.value)
+	return f.w.Close()
+}
+
+// loop closes files that were not written to for the idle timeout
+func (w *routeWriter) loop() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.idle / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case now := <-ticker.C:
+			w.mu.Lock()
+			// Files are ordered by last use, so the idle ones are at the back
+			for el := w.order.Back(); el != nil && now.Sub(el.Value.(*routeFile).used) >= w.idle; el = w.order.Back() {
+				_ = w.closeLocked(el)
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// Close stops the idle loop and closes every routed file
+func (w *routeWriter) Close() error {
+	if w.stop != nil {
+		close(w.stop)
+		<-w.done
+		w.stop = nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var errs []error
+	for el := w.order.Back(); el != nil; el = w.order.Back() {
+		errs = append(errs, w.closeLocked(el))
+	}
+	errs = append(errs, w.dir.Close())
+	return errors.Join(errs...)
+}
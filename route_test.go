@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRouteByField(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := New(Config{LogDir: tmpDir, Filename: "main.log", Route: RouteConfig{Field: "tenant_id"}})
+	logger.Info().Str("tenant_id", "acme").Msg("acme entry")
+	logger.WithField("tenant_id", "globex").Info().Msg("globex entry")
+	logger.Info().Int("tenant_id", 42).Msg("numeric tenant")
+	logger.Info().Msg("no tenant")
+	logger.Info().Str("tenant_id", "../escape").Msg("invalid tenant")
+	logger.Info().Str("tenant_id", "").Msg("empty tenant")
+	logger.Close()
+
+	for file, want := range map[string]string{
+		"tenant_id/acme/acme.log":     "acme entry",
+		"tenant_id/globex/globex.log": "globex entry",
+		"tenant_id/42/42.log":         "numeric tenant",
+	} {
+		logStr := readLogFile(t, filepath.Join(tmpDir, file))
+		if strings.Count(logStr, "\n") != 1 || !strings.Contains(logStr, want) {
+			t.Errorf("Expected only %q in %s, got %s", want, file, logStr)
+		}
+	}
+	mainStr := readLogFile(t, filepath.Join(tmpDir, "main.log"))
+	for _, msg := range []string{"no tenant", "invalid tenant", "empty tenant"} {
+		if !strings.Contains(mainStr, msg) {
+			t.Errorf("Expected %q in the main file: %s", msg, mainStr)
+		}
+	}
+	if strings.Contains(mainStr, "acme entry") {
+		t.Errorf("Did not expect routed entries in the main file: %s", mainStr)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "escape.log")); !os.IsNotExist(err) {
+		t.Error("Expected no file outside the routing directory")
+	}
+}
+
+func TestRouteFallsBackToMain(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := New(Config{LogDir: tmpDir, Filename: "main.log", Route: RouteConfig{Field: "tenant"}})
+	// A file where the value's directory should be makes the open fail
+	if err := os.WriteFile(filepath.Join(tmpDir, "tenant", "blocked"), nil, 0600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	logger.Info().Str("tenant", strings.Repeat("a", 300)).Msg("long tenant")
+	logger.Info().Str("tenant", "blocked").Msg("blocked tenant")
+	logger.Close()
+
+	mainStr := readLogFile(t, filepath.Join(tmpDir, "main.log"))
+	for _, msg := range []string{"long tenant", "blocked tenant"} {
+		if !strings.Contains(mainStr, msg) {
+			t.Errorf("Expected %q in the main file: %s", msg, mainStr)
+		}
+	}
+}
+
+func TestRouteSync(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := New(Config{LogDir: tmpDir, Filename: "main.log", Sync: SyncConfig{Every: 1}, Route: RouteConfig{Field: "tenant"}})
+	defer logger.Close()
+	logger.Info().Str("tenant", "acme").Msg("opens the file")
+
+	// Record the syncs of the routed file
+	rw := logger.closers[0].(*routeWriter)
+	f := &faultyFile{}
+	rw.mu.Lock()
+	rw.files["acme"].Value.(*routeFile).w.sync = f
+	rw.mu.Unlock()
+
+	logger.Info().Str("tenant", "acme").Msg("synced by the policy")
+	if _, syncs, _ := f.state(); syncs != 1 {
+		t.Errorf("Expected the sync policy to sync the routed file once, got %d", syncs)
+	}
+	if err := logger.Sync(); err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	if _, syncs, _ := f.state(); syncs != 2 {
+		t.Errorf("Expected Logger.Sync to sync the routed file, got %d syncs", syncs)
+	}
+}
+
+func TestRouteBoundedHandles(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := New(Config{LogDir: tmpDir, Route: RouteConfig{Field: "tenant", Dir: "tenants", MaxOpen: 2}})
+	rw := logger.closers[0].(*routeWriter)
+	for _, tenant := range []string{"a", "b", "c", "a"} {
+		logger.Info().Str("tenant", tenant).Msg("entry for " + tenant)
+	}
+	if len(rw.files) != 2 || rw.order.Len() != 2 {
+		t.Errorf("Expected 2 open files, got %d", len(rw.files))
+	}
+	if _, ok := rw.files["b"]; ok {
+		t.Error("Expected the least recently used file to be closed")
+	}
+	logger.Close()
+
+	// A reopened file is appended to
+	if logStr := readLogFile(t, filepath.Join(tmpDir, "tenants", "a", "a.log")); strings.Count(logStr, "entry for a") != 2 {
+		t.Errorf("Expected both entries for a: %s", logStr)
+	}
+}
+
+func TestRouteClosesIdleFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := New(Config{LogDir: tmpDir, Route: RouteConfig{Field: "tenant", IdleTimeout: 40 * time.Millisecond}})
+	defer logger.Close()
+	rw := logger.closers[0].(*routeWriter)
+	logger.Info().Str("tenant", "idle").Msg("entry")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		rw.mu.Lock()
+		open := len(rw.files)
+		rw.mu.Unlock()
+		if open == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("Expected the idle file to be closed")
+}
+
+func TestRouteInvalidDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := New(Config{LogDir: tmpDir, Filename: "main.log", Route: RouteConfig{Field: "tenant", Dir: "../outside"}})
+	logger.Info().Str("tenant", "acme").Msg("unrouted")
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "main.log"))
+	if !strings.Contains(logStr, "Failed to set up log routing") || !strings.Contains(logStr, "unrouted") {
+		t.Errorf("Expected the error and the entry in the main file: %s", logStr)
+	}
+}
+
+func TestRouteInsideAllowedRoot(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := New(Config{AllowedRoot: tmpDir, LogDir: "logs", Route: RouteConfig{Field: "tenant"}})
+	logger.Info().Str("tenant", "acme").Msg("confined")
+	logger.Close()
+
+	if logStr := readLogFile(t, filepath.Join(tmpDir, "logs", "tenant", "acme", "acme.log")); !strings.Contains(logStr, "confined") {
+		t.Errorf("Expected the routed file inside AllowedRoot: %s", logStr)
+	}
+}
+
+func TestRouteValueLikeBackupName(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := New(Config{LogDir: tmpDir, MaxBackups: 1, Route: RouteConfig{Field: "tenant"}})
+	rw := logger.closers[0].(*routeWriter)
+
+	// The second value reads like a backup of the first one's file
+	other := "a-" + time.Now().UTC().Format(backupTimeFormat)
+	logger.Info().Str("tenant", other).Msg("other tenant")
+	logger.Info().Str("tenant", "a").Msg("first")
+	for range 2 {
+		rw.mu.Lock()
+		err := rw.files["a"].Value.(*routeFile).w.w.(*rotatingWriter).Rotate()
+		rw.mu.Unlock()
+		if err != nil {
+			t.Fatalf("Rotate returned error: %v", err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	logger.Close()
+
+	if logStr := readLogFile(t, filepath.Join(tmpDir, "tenant", other, other+".log")); !strings.Contains(logStr, "other tenant") {
+		t.Errorf("Expected the other tenant's file to survive rotation of a: %s", logStr)
+	}
+}
+
+func TestRouteHashChainAndTextFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger := New(Config{
+		LogDir:     tmpDir,
+		FileFormat: FormatText,
+		HashChain:  HashChainConfig{Enabled: true},
+		Route:      RouteConfig{Field: "tenant"},
+	})
+	logger.Info().Str("tenant", "acme").Msg("one")
+	logger.Info().Str("tenant", "acme").Msg("two")
+	logger.Close()
+
+	dir := filepath.Join(tmpDir, "tenant", "acme")
+	logStr := readLogFile(t, filepath.Join(dir, "acme.log"))
+	if !strings.HasPrefix(logStr, `{"logchain":"header"`) || strings.Contains(logStr, `"message":"one"`) || !strings.Contains(logStr, "one") {
+		t.Errorf("Expected a chained text file: %s", logStr)
+	}
+	v, err := verifyChain(t, nil, chainFiles(t, dir, "acme.log")...)
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if v.LastSeq() != 2 {
+		t.Errorf("Expected 2 chained entries, got %d", v.LastSeq())
+	}
+}
@@ -0,0 +1,198 @@
+package logger
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxSampledMessages bounds the per-message samplers of one rule; further
+// messages share a single sampler
+const maxSampledMessages = 1024
+
+// SamplingConfig thins out high-volume levels. Levels without a rule are
+// always kept. Entries dropped by a sampler are counted in Stats.
+type SamplingConfig struct {
+	Levels map[string]SampleRule // Rules by level name, e.g. "debug" or "info"
+
+	// TraceRatio keeps every entry of this fraction of traces, so sampled
+	// traces stay complete. The decision is made from the trace ID of the
+	// span in the entry's context, with the same algorithm as OpenTelemetry's
+	// TraceIDRatioBased sampler, so services with the same ratio keep the
+	// same traces. Entries of other traces and entries without a trace go
+	// through the level rules. (default: 0, off)
+	TraceRatio float64
+}
+
+// SampleRule is a zerolog burst sampler followed by a basic 1-in-N sampler
+type SampleRule struct {
+	Burst      uint32        // Entries kept per Period before Every applies (default: 0, no burst)
+	Period     time.Duration // Burst window (default: 1s)
+	Every      uint32        // Keep 1 of every Every entries after the burst; 0 keeps none after a burst and all without one
+	PerMessage bool          // Apply the rule to each message text separately
+}
+
+// sampler builds the zerolog sampler for r, nil when r keeps everything
+func (r SampleRule) sampler() zerolog.Sampler {
+	if r.Every == 1 || (r.Every == 0 && r.Burst == 0) {
+		return nil
+	}
+	var next zerolog.Sampler
+	if r.Every > 1 {
+		next = &zerolog.BasicSampler{N: r.Every}
+	}
+	if r.Burst == 0 {
+		return next
+	}
+	period := r.Period
+	if period <= 0 {
+		period = time.Second
+	}
+	return &zerolog.BurstSampler{Burst: r.Burst, Period: period, NextSampler: next}
+}
+
+// sampleHook drops entries according to a SamplingConfig. It runs as a hook
+// rather than a zerolog.Sampler, because trace-consistent and per-message
+// sampling need the entry's context and message.
+type sampleHook struct {
+	traceBound uint64 // Trace IDs whose low 63 bits are below it are kept, 0 when off
+	levels     map[zerolog.Level]*levelSampler
+}
+
+// levelSampler applies one rule and counts what it drops
+type levelSampler struct {
+	rule    SampleRule
+	shared  zerolog.Sampler // Used without PerMessage, and for messages beyond maxSampledMessages
+	dropped atomic.Uint64
+
+	mu       sync.Mutex
+	messages map[string]*messageSampler
+}
+
+// messageSampler is the sampler of one message under a PerMessage rule
+type messageSampler struct {
+	sampler zerolog.Sampler
+	dropped atomic.Uint64
+}
+
+// newSampleHook builds the hook for cfg. Rules for unknown level names are
+// skipped and reported in the returned error.
+func newSampleHook(cfg SamplingConfig) (*sampleHook, error) {
+	h := &sampleHook{levels: make(map[zerolog.Level]*levelSampler)}
+	if cfg.TraceRatio >= 1 {
+		h.traceBound = 1 << 63
+	} else if cfg.TraceRatio > 0 {
+		h.traceBound = uint64(cfg.TraceRatio * (1 << 63))
+	}
+
+	var errs []error
+	for name, rule := range cfg.Levels {
+		level, err := zerolog.ParseLevel(name)
+		if err != nil || level == zerolog.NoLevel {
+			errs = append(errs, fmt.Errorf("unknown sampling level %q", name))
+			continue
+		}
+		s := rule.sampler()
+		if s == nil {
+			continue
+		}
+		h.levels[level] = &levelSampler{rule: rule, shared: s, messages: make(map[string]*messageSampler)}
+	}
+	return h, errors.Join(errs...)
+}
+
+// Run implements zerolog.Hook
+func (h *sampleHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	s := h.levels[level]
+	if s == nil || h.traceKept(e) {
+		return
+	}
+	if !s.sample(level, msg) {
+		e.Discard()
+	}
+}
+
+// traceKept reports whether the entry belongs to a trace kept by TraceRatio
+func (h *sampleHook) traceKept(e *zerolog.Event) bool {
+	if h.traceBound == 0 {
+		return false
+	}
+	ctx := e.GetCtx()
+	if ctx == nil {
+		return false
+	}
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return false
+	}
+	tid := sc.TraceID()
+	return binary.BigEndian.Uint64(tid[8:16])>>1 < h.traceBound
+}
+
+// sample reports whether to keep an entry, counting it if not
+func (s *levelSampler) sample(level zerolog.Level, msg string) bool {
+	var m *messageSampler
+	if s.rule.PerMessage {
+		m = s.message(msg)
+	}
+	if m == nil {
+		if s.shared.Sample(level) {
+			return true
+		}
+		s.dropped.Add(1)
+		return false
+	}
+	if m.sampler.Sample(level) {
+		return true
+	}
+	m.dropped.Add(1)
+	s.dropped.Add(1)
+	return false
+}
+
+// message returns the sampler of msg, nil once maxSampledMessages is reached
+func (s *levelSampler) message(msg string) *messageSampler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m, ok := s.messages[msg]; ok {
+		return m
+	}
+	if len(s.messages) >= maxSampledMessages {
+		return nil
+	}
+	m := &messageSampler{sampler: s.rule.sampler()}
+	s.messages[msg] = m
+	return m
+}
+
+// addStats adds the drop counters to st
+func (h *sampleHook) addStats(st *Stats) {
+	for level, s := range h.levels {
+		n := s.dropped.Load()
+		if n == 0 {
+			continue
+		}
+		if st.SampledOutByLevel == nil {
+			st.SampledOutByLevel = make(map[string]uint64)
+		}
+		st.SampledOut += n
+		st.SampledOutByLevel[level.String()] += n
+
+		s.mu.Lock()
+		for msg, m := range s.messages {
+			if d := m.dropped.Load(); d > 0 {
+				if st.SampledOutByMessage == nil {
+					st.SampledOutByMessage = make(map[string]uint64)
+				}
+				st.SampledOutByMessage[msg] += d
+			}
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,181 @@
+package logger
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSamplingPerLevel(t *testing.T) {
+	logger, path := newTestLogger(t, Config{Level: "debug", Sampling: SamplingConfig{Levels: map[string]SampleRule{
+		"debug": {Burst: 5, Period: time.Hour},
+		"info":  {Every: 10},
+	}}})
+	for range 100 {
+		logger.Debug().Msg("debug entry")
+		logger.Info().Msg("info entry")
+		logger.Warn().Msg("warn entry")
+	}
+	st := logger.Stats()
+	logger.Close()
+
+	logStr := readLogFile(t, path)
+	for msg, want := range map[string]int{"debug entry": 5, "info entry": 10, "warn entry": 100} {
+		if got := strings.Count(logStr, msg); got != want {
+			t.Errorf("Expected %d %q entries, got %d", want, msg, got)
+		}
+	}
+	if st.SampledOut != 185 || st.SampledOutByLevel["debug"] != 95 || st.SampledOutByLevel["info"] != 90 {
+		t.Errorf("Unexpected drop counters: %+v", st)
+	}
+	if _, ok := st.SampledOutByLevel["warn"]; ok {
+		t.Errorf("Did not expect drops for warn: %+v", st.SampledOutByLevel)
+	}
+	if st.SampledOutByMessage != nil {
+		t.Errorf("Did not expect per-message counters without PerMessage: %+v", st.SampledOutByMessage)
+	}
+}
+
+func TestSamplingBurstThenEvery(t *testing.T) {
+	logger, path := newTestLogger(t, Config{Level: "debug", Sampling: SamplingConfig{Levels: map[string]SampleRule{
+		"info": {Burst: 3, Period: time.Hour, Every: 5},
+	}}})
+	for range 23 {
+		logger.Info().Msg("entry")
+	}
+	logger.Close()
+
+	// 3 in the burst, then 1 in 5 of the remaining 20
+	if got := strings.Count(readLogFile(t, path), `"message":"entry"`); got != 7 {
+		t.Errorf("Expected 7 entries, got %d", got)
+	}
+}
+
+func TestSamplingPerMessage(t *testing.T) {
+	logger, path := newTestLogger(t, Config{Level: "debug", Sampling: SamplingConfig{Levels: map[string]SampleRule{
+		"info": {Burst: 2, Period: time.Hour, PerMessage: true},
+	}}})
+	for range 10 {
+		logger.Info().Msg("noisy")
+	}
+	logger.Info().Msg("rare")
+	st := logger.Stats()
+	logger.Close()
+
+	logStr := readLogFile(t, path)
+	if strings.Count(logStr, `"message":"noisy"`) != 2 || !strings.Contains(logStr, `"message":"rare"`) {
+		t.Errorf("Expected a separate burst per message: %s", logStr)
+	}
+	if st.SampledOut != 8 || st.SampledOutByMessage["noisy"] != 8 || len(st.SampledOutByMessage) != 1 {
+		t.Errorf("Unexpected drop counters: %+v", st)
+	}
+}
+
+func TestSamplingPerMessageBound(t *testing.T) {
+	h, err := newSampleHook(SamplingConfig{Levels: map[string]SampleRule{
+		"info": {Burst: 1, Period: time.Hour, PerMessage: true},
+	}})
+	if err != nil {
+		t.Fatalf("newSampleHook returned error: %v", err)
+	}
+	s := h.levels[zerolog.InfoLevel]
+	for i := range maxSampledMessages + 10 {
+		s.sample(zerolog.InfoLevel, "msg "+strconv.Itoa(i))
+	}
+	if len(s.messages) != maxSampledMessages {
+		t.Errorf("Expected %d message samplers, got %d", maxSampledMessages, len(s.messages))
+	}
+	// Messages past the bound share one burst
+	if s.dropped.Load() != 9 {
+		t.Errorf("Expected 9 drops from the shared sampler, got %d", s.dropped.Load())
+	}
+}
+
+func TestSamplingKeepsWholeTraces(t *testing.T) {
+	logger, path := newTestLogger(t, Config{Level: "debug", Sampling: SamplingConfig{
+		Levels:     map[string]SampleRule{"debug": {Burst: 1, Period: time.Hour}},
+		TraceRatio: 0.5,
+	}})
+
+	// The decision uses the low 63 bits of the trace ID, as OpenTelemetry does
+	kept, _ := ContextWithTraceparent(context.Background(), "00-ffffffffffffffff0000000000000001-00f067aa0ba902b7-01")
+	other, _ := ContextWithTraceparent(context.Background(), "00-0000000000000001ffffffffffffffff-00f067aa0ba902b7-01")
+	for range 10 {
+		logger.Ctx(kept).Debug().Msg("kept trace")
+		logger.Ctx(other).Debug().Msg("other trace")
+		logger.Ctx(context.Background()).Debug().Msg("no trace")
+	}
+	st := logger.Stats()
+	logger.Close()
+
+	logStr := readLogFile(t, path)
+	if got := strings.Count(logStr, "kept trace"); got != 10 {
+		t.Errorf("Expected every entry of the sampled trace, got %d", got)
+	}
+	if got := strings.Count(logStr, "other trace") + strings.Count(logStr, "no trace"); got != 1 {
+		t.Errorf("Expected other entries to share the debug burst, got %d", got)
+	}
+	if st.SampledOut != 19 {
+		t.Errorf("Expected 19 drops, got %d", st.SampledOut)
+	}
+}
+
+func TestSamplingAfterSpanEvents(t *testing.T) {
+	sc, _ := ParseTraceparent(testTraceparent)
+	span := &recordingSpan{sc: sc}
+	tmpDir := t.TempDir()
+	logger := New(Config{
+		LogDir:          tmpDir,
+		Filename:        "sample.log",
+		TraceSpanEvents: true,
+		Sampling:        SamplingConfig{Levels: map[string]SampleRule{"error": {Burst: 1, Period: time.Hour}}},
+	})
+	traced := logger.Ctx(trace.ContextWithSpan(context.Background(), span))
+	traced.Error().Msg("first error")
+	traced.Error().Msg("second error")
+	logger.Close()
+
+	if logStr := readLogFile(t, filepath.Join(tmpDir, "sample.log")); strings.Contains(logStr, "second error") {
+		t.Errorf("Expected the second error to be sampled out of the file: %s", logStr)
+	}
+	if len(span.events) != 2 {
+		t.Errorf("Expected both errors on the span, got %d events", len(span.events))
+	}
+}
+
+func TestSamplingUnknownLevel(t *testing.T) {
+	logger, path := newTestLogger(t, Config{Level: "debug", Sampling: SamplingConfig{Levels: map[string]SampleRule{
+		"verbose": {Every: 10},
+		"info":    {Every: 2},
+	}}})
+	logger.Info().Msg("one")
+	logger.Info().Msg("two")
+	logger.Close()
+
+	logStr := readLogFile(t, path)
+	if !strings.Contains(logStr, "Invalid sampling configuration") || !strings.Contains(logStr, `unknown sampling level \"verbose\"`) {
+		t.Errorf("Expected the unknown level to be reported: %s", logStr)
+	}
+	if strings.Contains(logStr, `"message":"one"`) == strings.Contains(logStr, `"message":"two"`) {
+		t.Errorf("Expected valid rules to still apply: %s", logStr)
+	}
+}
+
+func TestSamplingKeepAllRules(t *testing.T) {
+	h, err := newSampleHook(SamplingConfig{Levels: map[string]SampleRule{
+		"debug": {},
+		"info":  {Every: 1},
+	}})
+	if err != nil {
+		t.Fatalf("newSampleHook returned error: %v", err)
+	}
+	if len(h.levels) != 0 {
+		t.Errorf("Expected rules that keep everything to be skipped, got %v", h.levels)
+	}
+}
@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+)
+
+// unsafeRune reports whether r can change how a terminal or text viewer
+// renders the surrounding output: control characters, bidi formatting
+// characters (Trojan Source) and Unicode line separators
+func unsafeRune(r rune) bool {
+	switch {
+	case r < 0x20, r == 0x7f:
+		return true
+	case r >= 0x80 && r <= 0x9f: // C1 controls, including the 8-bit CSI
+		return true
+	case r == 0x061c, r == 0x200e, r == 0x200f: // Bidi marks
+		return true
+	case r >= 0x202a && r <= 0x202e, r >= 0x2066 && r <= 0x2069: // Bidi embeddings, overrides and isolates
+		return true
+	case r == 0x2028, r == 0x2029:
+		return true
+	}
+	return false
+}
+
+// needsSanitizing reports whether s contains unsafe runes or invalid UTF-8
+func needsSanitizing(s string) bool {
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= 0x20 && c < 0x7f {
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if (r == utf8.RuneError && size == 1) || unsafeRune(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// sanitizeText replaces unsafe runes with visible escapes (\n, \x1b, \u202e)
+// and invalid UTF-8 bytes with \xNN, so the result prints on a single line
+// without affecting the terminal
+func sanitizeText(s string) string {
+	if !needsSanitizing(s) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b.WriteString(`\x`)
+			b.WriteByte(hexDigits[s[i]>>4])
+			b.WriteByte(hexDigits[s[i]&0xf])
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < utf8.RuneSelf && unsafeRune(r):
+			b.WriteString(`\x`)
+			b.WriteByte(hexDigits[r>>4])
+			b.WriteByte(hexDigits[r&0xf])
+		case unsafeRune(r):
+			b.WriteString(`\u`)
+			b.WriteByte(hexDigits[r>>12&0xf])
+			b.WriteByte(hexDigits[r>>8&0xf])
+			b.WriteByte(hexDigits[r>>4&0xf])
+			b.WriteByte(hexDigits[r&0xf])
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// escapeUnsafeJSON appends p to dst with unsafe runes written as \uXXXX
+// escapes and invalid UTF-8 as \ufffd. Inside JSON strings this keeps the
+// decoded value intact while the raw bytes stay terminal-safe. Newlines and
+// tabs are kept since they separate entries and are valid JSON whitespace.
+func escapeUnsafeJSON(dst, p []byte) []byte {
+	for i := 0; i < len(p); {
+		c := p[i]
+		if (c >= 0x20 && c < 0x7f) || c == '\n' || c == '\t' {
+			dst = append(dst, c)
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(p[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			dst = append(dst, `\ufffd`...)
+		case unsafeRune(r):
+			dst = append(dst, '\\', 'u', hexDigits[r>>12&0xf], hexDigits[r>>8&0xf], hexDigits[r>>4&0xf], hexDigits[r&0xf])
+		default:
+			dst = append(dst, p[i:i+size]...)
+		}
+		i += size
+	}
+	return dst
+}
+
+// sanitizingWriter escapes unsafe characters in everything written to next
+type sanitizingWriter struct {
+	next io.Writer
+}
+
+// Write implements io.Writer
+func (w sanitizingWriter) Write(p []byte) (int, error) {
+	if !needsSanitizing(string(p)) {
+		return w.next.Write(p)
+	}
+	if _, err := w.next.Write(escapeUnsafeJSON(make([]byte, 0, len(p)+16), p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// sanitizeConsoleEvent is the console writer's FormatPrepare hook. The
+// writer prints field names and the level, time, message and caller parts
+// verbatim, so those get visible escapes. Top-level string values are
+// already quoted with strconv.Quote, and nested values are re-encoded with
+// unsafe runes escaped.
+func sanitizeConsoleEvent(evt map[string]interface{}) error {
+	keys := make([]string, 0, len(evt))
+	for key := range evt {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		value := evt[key]
+		switch key {
+		case zerolog.LevelFieldName, zerolog.TimestampFieldName, zerolog.MessageFieldName, zerolog.CallerFieldName:
+			if s, ok := value.(string); ok {
+				value = sanitizeText(s)
+			}
+		}
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			if b, err := json.Marshal(value); err == nil {
+				value = json.RawMessage(escapeUnsafeJSON(nil, b))
+			}
+		}
+		if safe := sanitizeText(key); safe != key {
+			delete(evt, key)
+			key = safe
+		}
+		evt[key] = value
+	}
+	return nil
+}
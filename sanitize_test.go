@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// attackStrings are payloads that tamper with terminals or forge log lines
+var attackStrings = map[string]string{
+	"clear screen":        "\x1b[2J\x1b[Hsystem compromised",
+	"window title":        "\x1b]0;pwned\x07",
+	"hyperlink":           "\x1b]8;;http://evil.example\x1b\\click\x1b]8;;\x1b\\",
+	"crlf line forging":   "ok\r\n2026-01-01 00:00:00 INF admin login succeeded",
+	"carriage overwrite":  "denied\r\x1b[Kgranted",
+	"c1 csi":              "\xc2\x9b31mred",
+	"right-to-left":       "user\xe2\x80\xaetxt.exe",
+	"bidi isolate":        "a\xe2\x81\xa6b\xe2\x81\xa9c",
+	"line separator":      "one\xe2\x80\xa8two",
+	"invalid utf-8":       "bad\xff\xfebytes",
+	"nul and del":         "nul\x00del\x7f",
+	"backspace overwrite": "secret\x08\x08\x08\x08\x08\x08public",
+}
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain text", "plain text"},
+		{"héllo 世界", "héllo 世界"},
+		{"a\nb\r\tc", `a\nb\r\tc`},
+		{"\x1b[31mred", `\x1b[31mred`},
+		{"\x7f", `\x7f`},
+		{"\xc2\x9b", `\u009b`},
+		{"x\xe2\x80\xaey", `x\u202ey`},
+		{"\xe2\x80\xa8", `\u2028`},
+		{"bad\xffbyte", `bad\xffbyte`},
+	}
+	for _, tt := range tests {
+		if got := sanitizeText(tt.in); got != tt.want {
+			t.Errorf("sanitizeText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeUnsafeJSONKeepsValue(t *testing.T) {
+	for name, attack := range attackStrings {
+		t.Run(name, func(t *testing.T) {
+			encoded, _ := json.Marshal(map[string]string{"msg": attack})
+			escaped := escapeUnsafeJSON(nil, encoded)
+
+			if needsSanitizing(string(escaped)) {
+				t.Errorf("Unsafe bytes remain: %q", escaped)
+			}
+			var decoded map[string]string
+			if err := json.Unmarshal(escaped, &decoded); err != nil {
+				t.Fatalf("Escaped output is not valid JSON: %v", err)
+			}
+			var want map[string]string
+			json.Unmarshal(encoded, &want)
+			if decoded["msg"] != want["msg"] {
+				t.Errorf("Decoded value changed: got %q, want %q", decoded["msg"], want["msg"])
+			}
+		})
+	}
+}
+
+func TestConsoleWriterAttackStrings(t *testing.T) {
+	t.Setenv("NO_COLOR", "1") // The writer's own color codes are not under test
+
+	for name, attack := range attackStrings {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			zl := zerolog.New(newConsoleWriter(&buf)).With().Timestamp().Logger()
+
+			zl.Info().
+				Str("value", attack).
+				Str("key"+attack, "v").
+				Interface("nested", map[string]interface{}{"list": []string{attack}}).
+				Err(errors.New(attack)).
+				Msg(attack)
+			zl.WithLevel(zerolog.InfoLevel).Str(zerolog.CallerFieldName, attack).Str(zerolog.LevelFieldName, attack).Msg("parts")
+
+			out := buf.String()
+			lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("Expected 2 console lines, got %d: %q", len(lines), out)
+			}
+			for _, line := range lines {
+				if needsSanitizing(line) {
+					t.Errorf("Unsafe characters reached the console: %q", line)
+				}
+			}
+		})
+	}
+}
+
+func TestConsoleWriterSafeValuesUnchanged(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	var buf bytes.Buffer
+	zl := zerolog.New(newConsoleWriter(&buf))
+	zl.Info().Str("user", "zoë").Msg("héllo 世界")
+
+	if got := buf.String(); got != "<nil> INF héllo 世界 user=\"zoë\"\n" {
+		t.Errorf("Unexpected console output %q", got)
+	}
+}
+
+func TestLoggerSanitizeFile(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "sanitized.log", SanitizeFile: true, DisableCaller: true})
+	for _, attack := range attackStrings {
+		logger.Info().Str("value", attack).Msg(attack)
+	}
+	logger.Info().RawJSON("raw", []byte("\"raw\xe2\x80\xae\xff\"")).Msg("raw json")
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "sanitized.log"))
+	lines := strings.Split(strings.TrimSuffix(logStr, "\n"), "\n")
+	if len(lines) != len(attackStrings)+1 {
+		t.Fatalf("Expected %d lines, got %d: %q", len(attackStrings)+1, len(lines), logStr)
+	}
+	for _, line := range lines {
+		if needsSanitizing(line) {
+			t.Errorf("Unsafe characters reached the file: %q", line)
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Errorf("Line is not valid JSON: %v: %q", err, line)
+		}
+	}
+}
+
+func TestLoggerFileUnsanitizedByDefault(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "raw.log"})
+	logger.Info().Msg("a\xe2\x80\xaeb")
+	logger.Close()
+
+	if !strings.Contains(readLogFile(t, filepath.Join(tmpDir, "raw.log")), "a\xe2\x80\xaeb") {
+		t.Error("Expected file output to keep valid UTF-8 unchanged without SanitizeFile")
+	}
+}
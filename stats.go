@@ -0,0 +1,40 @@
+package logger
+
+import "sync/atomic"
+
+// Stats is a snapshot of the counters shared by a logger and every logger
+// derived from it
+type Stats struct {
+	Truncated  uint64 // Messages, field values and entries shortened by Limits
+	SyncErrors uint64 // Failed fsync calls made by the Sync policy
+
+	SampledOut          uint64            // Entries dropped by Sampling
+	SampledOutByLevel   map[string]uint64 // Dropped entries by level name
+	SampledOutByMessage map[string]uint64 // Dropped entries by message, for PerMessage rules
+
+	Deduplicated uint64 // Duplicate entries suppressed by Dedup
+}
+
+// loggerStats holds the live counters behind Stats
+type loggerStats struct {
+	truncated    atomic.Uint64
+	syncErrors   atomic.Uint64
+	deduplicated atomic.Uint64
+	sampling     *sampleHook // Drop counters, nil without Sampling
+}
+
+// Stats returns a snapshot of the logger's counters
+func (l *Logger) Stats() Stats {
+	if l.stats == nil {
+		return Stats{}
+	}
+	st := Stats{
+		Truncated:    l.stats.truncated.Load(),
+		SyncErrors:   l.stats.syncErrors.Load(),
+		Deduplicated: l.stats.deduplicated.Load(),
+	}
+	if l.stats.sampling != nil {
+		l.stats.sampling.addStats(&st)
+	}
+	return st
+}
@@ -0,0 +1,167 @@
+//go:build linux
+
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/sys/unix"
+)
+
+// stdioDrainTimeout bounds how long Close waits for captured output still in
+// flight, for example when a child process inherited the pipe
+const stdioDrainTimeout = time.Second
+
+var (
+	stdioMu     sync.Mutex
+	stdioActive bool     // Only one capture can own fd 1 and 2 per process
+	stdioStderr *os.File // The original stderr while a capture is active
+
+	// stdioPrevHandler is the zerolog.ErrorHandler replaced by the capture
+	stdioPrevHandler func(err error)
+)
+
+// stderrWriter writes to the process's original stderr, bypassing an active
+// capture so that reports of failed writes are not captured and logged again
+type stderrWriter struct{}
+
+// Write implements io.Writer
+func (stderrWriter) Write(p []byte) (int, error) {
+	stdioMu.Lock()
+	defer stdioMu.Unlock()
+	if stdioStderr != nil {
+		return stdioStderr.Write(p)
+	}
+	return os.Stderr.Write(p)
+}
+
+// reportWriteError is the zerolog.ErrorHandler while a capture is active
+func reportWriteError(err error) {
+	fmt.Fprintf(stderrWriter{}, "zerolog: could not write event: %v\n", err)
+}
+
+// stdioStream is one redirected file descriptor
+type stdioStream struct {
+	fd     int
+	name   string
+	level  zerolog.Level
+	saved  *os.File // Duplicate of the original descriptor
+	reader *os.File // Read end of the pipe installed on fd, nil until started
+	writer *LineWriter
+	done   chan struct{}
+}
+
+// stdioCapture redirects the process's stdout and stderr file descriptors
+// into the logger and restores them on Close
+type stdioCapture struct {
+	streams   []*stdioStream
+	closeOnce sync.Once
+}
+
+// newStdioCapture claims fd 1 and 2 for this process and keeps duplicates of
+// the originals, so console output can still reach the real terminal. Lines
+// from stdout are logged at info level and lines from stderr at warn level
+// once start is called.
+func newStdioCapture() (*stdioCapture, error) {
+	stdioMu.Lock()
+	defer stdioMu.Unlock()
+	if stdioActive {
+		return nil, errors.New("stdout/stderr are already captured by another logger")
+	}
+
+	c := &stdioCapture{}
+	for _, s := range []*stdioStream{
+		{fd: unix.Stdout, name: "stdout", level: zerolog.InfoLevel},
+		{fd: unix.Stderr, name: "stderr", level: zerolog.WarnLevel},
+	} {
+		savedFD, err := unix.FcntlInt(uintptr(s.fd), unix.F_DUPFD_CLOEXEC, 0)
+		if err != nil {
+			for _, prev := range c.streams {
+				_ = prev.saved.Close()
+			}
+			return nil, err
+		}
+		s.saved = os.NewFile(uintptr(savedFD), "saved-"+s.name)
+		c.streams = append(c.streams, s)
+	}
+
+	stdioActive = true
+	stdioStderr = c.streams[1].saved
+	stdioPrevHandler, zerolog.ErrorHandler = zerolog.ErrorHandler, reportWriteError
+	return c, nil
+}
+
+// start installs the write end of a new pipe on each descriptor and logs
+// every line read from the other end through l
+func (c *stdioCapture) start(l *Logger) error {
+	for _, s := range c.streams {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		if err := unix.Dup3(int(w.Fd()), s.fd, 0); err != nil {
+			r.Close()
+			w.Close()
+			return err
+		}
+		// fd now refers to the pipe; the original write end is no longer needed
+		w.Close()
+
+		stream := s.name
+		s.reader = r
+		s.done = make(chan struct{})
+		s.writer = &LineWriter{logger: l, level: s.level}
+		s.writer.emit = func(level zerolog.Level, line string) {
+			l.WithLevel(level).Str("stream", stream).Msg(line)
+		}
+
+		go func(s *stdioStream) {
+			defer close(s.done)
+			_, _ = io.Copy(s.writer, s.reader)
+		}(s)
+	}
+	return nil
+}
+
+// console returns the original stdout so console output bypasses the capture
+func (c *stdioCapture) console() io.Writer {
+	return c.streams[0].saved
+}
+
+// Close restores the original stdout and stderr after draining the pipes
+func (c *stdioCapture) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		for _, s := range c.streams {
+			if s.reader == nil {
+				continue
+			}
+			// Replacing fd closes the pipe's write end so the reader sees EOF
+			err = errors.Join(err, unix.Dup3(int(s.saved.Fd()), s.fd, 0))
+
+			_ = s.reader.SetReadDeadline(time.Now().Add(stdioDrainTimeout))
+			<-s.done
+			_ = s.writer.Close()
+			_ = s.reader.Close()
+		}
+		c.release()
+	})
+	return err
+}
+
+// release closes the saved descriptors and frees fd 1 and 2 for another capture
+func (c *stdioCapture) release() {
+	stdioMu.Lock()
+	defer stdioMu.Unlock()
+	for _, s := range c.streams {
+		_ = s.saved.Close()
+	}
+	stdioActive, stdioStderr = false, nil
+	zerolog.ErrorHandler = stdioPrevHandler
+}
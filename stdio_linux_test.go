@@ -0,0 +1,129 @@
+//go:build linux
+
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// TestCaptureStdioHelper writes to the process's stdout and stderr when run as
+// a subprocess of TestCaptureStdio, so the test runner's own output is never
+// redirected
+func TestCaptureStdioHelper(t *testing.T) {
+	logDir := os.Getenv("GO_LOGGER_STDIO_DIR")
+	if logDir == "" {
+		t.Skip("helper process only")
+	}
+
+	logger := New(Config{LogDir: logDir, Filename: "stdio.log", Console: true, CaptureStdio: true})
+	logger.Info().Msg("logger message")
+
+	fmt.Println("stray println")
+	fmt.Fprintln(os.Stderr, "stray stderr")
+	syscall.Write(2, []byte("raw fd write\n"))
+	fmt.Print("partial without newline")
+
+	if err := logger.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "close failed:", err)
+		os.Exit(2)
+	}
+	fmt.Println("after close")
+}
+
+func TestCaptureStdio(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCaptureStdioHelper$")
+	cmd.Env = append(os.Environ(), "GO_LOGGER_STDIO_DIR="+tmpDir)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Helper process failed: %v\nstdout: %s\nstderr: %s", err, stdout.String(), stderr.String())
+	}
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "stdio.log"))
+	expected := []struct {
+		message string
+		stream  string
+	}{
+		{"stray println", "stdout"},
+		{"stray stderr", "stderr"},
+		{"raw fd write", "stderr"},
+		{"partial without newline", "stdout"},
+	}
+	for _, want := range expected {
+		var found bool
+		for _, line := range strings.Split(logStr, "\n") {
+			if strings.Contains(line, `"message":"`+want.message+`"`) {
+				found = true
+				if !strings.Contains(line, `"stream":"`+want.stream+`"`) {
+					t.Errorf("Expected stream %s for %q, got: %s", want.stream, want.message, line)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("Expected captured line %q in log file, got: %s", want.message, logStr)
+		}
+	}
+
+	if strings.Contains(logStr, "after close") {
+		t.Error("Output after Close should not be captured")
+	}
+
+	// Console output stays on the real terminal, and stdout works after Close
+	if !strings.Contains(stdout.String(), "logger message") {
+		t.Errorf("Expected console output on the original stdout, got: %s", stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "after close") {
+		t.Errorf("Expected stdout to be restored after Close, got: %s", stdout.String())
+	}
+	if strings.Contains(stderr.String(), "stray stderr") {
+		t.Error("Captured stderr should not reach the original stderr")
+	}
+}
+
+func TestCaptureStdioErrorOutput(t *testing.T) {
+	c, err := newStdioCapture()
+	if err != nil {
+		t.Fatalf("newStdioCapture returned error: %v", err)
+	}
+
+	// Write errors and fallback loggers go to the original stderr, which the
+	// capture would otherwise log and fail to write again
+	if zerolog.ErrorHandler == nil {
+		t.Error("Expected a zerolog.ErrorHandler while stdio is captured")
+	}
+	stdioMu.Lock()
+	saved := stdioStderr
+	stdioMu.Unlock()
+	if saved != c.streams[1].saved {
+		t.Error("Expected stderrWriter to use the saved stderr")
+	}
+
+	c.Close()
+	if zerolog.ErrorHandler != nil || stdioStderr != nil {
+		t.Error("Expected Close to restore the error output")
+	}
+}
+
+func TestCaptureStdioSingleOwner(t *testing.T) {
+	c, err := newStdioCapture()
+	if err != nil {
+		t.Fatalf("newStdioCapture returned error: %v", err)
+	}
+	defer c.Close()
+
+	if _, err := newStdioCapture(); err == nil {
+		t.Error("Expected second capture to be rejected while the first is active")
+	}
+}
@@ -0,0 +1,41 @@
+//go:build !linux
+
+package logger
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// stderrWriter writes to stderr, which is never captured on unsupported
+// platforms
+type stderrWriter struct{}
+
+// Write implements io.Writer
+func (stderrWriter) Write(p []byte) (int, error) {
+	return os.Stderr.Write(p)
+}
+
+// stdioCapture is only supported on Linux
+type stdioCapture struct{}
+
+// newStdioCapture reports that descriptor redirection is unavailable
+func newStdioCapture() (*stdioCapture, error) {
+	return nil, errors.New("stdout/stderr capture is only supported on linux")
+}
+
+// start is never called on unsupported platforms
+func (c *stdioCapture) start(*Logger) error {
+	return nil
+}
+
+// console is never called on unsupported platforms
+func (c *stdioCapture) console() io.Writer {
+	return nil
+}
+
+// Close is a no-op on unsupported platforms
+func (c *stdioCapture) Close() error {
+	return nil
+}
@@ -0,0 +1,187 @@
+package logger
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+)
+
+// maxPartialLine bounds the bytes buffered for a line without a newline;
+// longer lines are logged in pieces of this size
+const maxPartialLine = 64 * 1024
+
+// LineWriter is an io.WriteCloser that splits incoming bytes into lines and
+// logs each line as a separate entry. Partial lines are buffered until the
+// next newline or until Close is called.
+type LineWriter struct {
+	// ParseLevels enables parsing of level prefixes such as "[ERROR]" or
+	// "WARN:" at the start of a line. A recognized prefix is removed from the
+	// message and overrides the writer's level for that line.
+	ParseLevels bool
+
+	logger *Logger
+	level  zerolog.Level
+	emit   func(level zerolog.Level, line string) // Overrides logging of a complete line
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// Writer returns an io.Writer that logs each line written to it at level.
+// Call Close on the returned writer to flush a trailing partial line.
+func (l *Logger) Writer(level zerolog.Level) *LineWriter {
+	return &LineWriter{logger: l, level: level}
+}
+
+// StdLogger returns a standard library *log.Logger that writes each line
+// through l at level
+func (l *Logger) StdLogger(level zerolog.Level) *log.Logger {
+	return log.New(l.Writer(level), "", 0)
+}
+
+// RedirectStdLog sends the output of the standard library's global logger
+// through l. Level prefixes such as "[ERROR]" are parsed, and lines without
+// one are logged at info level. The returned function restores the previous
+// output, prefix and flags of the global logger.
+func (l *Logger) RedirectStdLog() func() {
+	w := l.Writer(zerolog.InfoLevel)
+	w.ParseLevels = true
+
+	prevOutput, prevPrefix, prevFlags := log.Writer(), log.Prefix(), log.Flags()
+	log.SetOutput(w)
+	log.SetPrefix("")
+	log.SetFlags(0)
+
+	return func() {
+		log.SetOutput(prevOutput)
+		log.SetPrefix(prevPrefix)
+		log.SetFlags(prevFlags)
+		_ = w.Close()
+	}
+}
+
+// Write implements io.Writer
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.buf = append(w.buf, p...)
+			for len(w.buf) > maxPartialLine {
+				cut := lineCut(w.buf)
+				w.logLine(w.buf[:cut])
+				w.buf = append(w.buf[:0], w.buf[cut:]...)
+			}
+			break
+		}
+
+		if len(w.buf) > 0 {
+			w.buf = append(w.buf, p[:i]...)
+			w.logLine(w.buf)
+			w.buf = w.buf[:0]
+		} else {
+			w.logLine(p[:i])
+		}
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// lineCut returns where to split a line longer than maxPartialLine: at the
+// limit, moved back to the start of a UTF-8 rune so that no rune is cut in two
+func lineCut(line []byte) int {
+	for cut := maxPartialLine; cut > maxPartialLine-utf8.UTFMax; cut-- {
+		if utf8.RuneStart(line[cut]) {
+			return cut
+		}
+	}
+	return maxPartialLine // Not UTF-8
+}
+
+// Close flushes a buffered partial line, if any
+func (w *LineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.logLine(w.buf)
+		w.buf = w.buf[:0]
+	}
+	return nil
+}
+
+// logLine logs a single line without its trailing newline
+func (w *LineWriter) logLine(line []byte) {
+	text := strings.TrimRight(string(line), "\r")
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	level := w.level
+	if w.ParseLevels {
+		if parsed, rest, ok := parseLevelPrefix(text); ok {
+			level, text = parsed, rest
+		}
+	}
+
+	if w.emit != nil {
+		w.emit(level, text)
+		return
+	}
+	w.logger.WithLevel(level).Msg(text)
+}
+
+// levelPrefixes maps recognized line prefixes to levels
+var levelPrefixes = map[string]zerolog.Level{
+	"TRACE":   zerolog.TraceLevel,
+	"DEBUG":   zerolog.DebugLevel,
+	"INFO":    zerolog.InfoLevel,
+	"NOTICE":  zerolog.InfoLevel,
+	"WARN":    zerolog.WarnLevel,
+	"WARNING": zerolog.WarnLevel,
+	"ERROR":   zerolog.ErrorLevel,
+	"ERR":     zerolog.ErrorLevel,
+	"FATAL":   zerolog.ErrorLevel, // Never exit the process on behalf of a library
+	"PANIC":   zerolog.ErrorLevel,
+	"CRIT":    zerolog.ErrorLevel,
+}
+
+// parseLevelPrefix recognizes "[LEVEL] msg" and "LEVEL: msg" prefixes in any
+// case, and bare "LEVEL msg" prefixes in upper case only so that ordinary
+// sentences such as "Error connecting" are left intact
+func parseLevelPrefix(line string) (zerolog.Level, string, bool) {
+	s := strings.TrimLeft(line, " \t")
+
+	var word, rest string
+	if strings.HasPrefix(s, "[") {
+		end := strings.IndexByte(s, ']')
+		if end < 0 {
+			return zerolog.NoLevel, line, false
+		}
+		word, rest = s[1:end], s[end+1:]
+	} else {
+		end := strings.IndexAny(s, ": \t")
+		if end < 0 {
+			return zerolog.NoLevel, line, false
+		}
+		word, rest = s[:end], s[end:]
+		if strings.HasPrefix(rest, ":") {
+			rest = rest[1:]
+		} else if word != strings.ToUpper(word) {
+			return zerolog.NoLevel, line, false
+		}
+	}
+
+	level, ok := levelPrefixes[strings.ToUpper(word)]
+	if !ok {
+		return zerolog.NoLevel, line, false
+	}
+	return level, strings.TrimLeft(rest, " \t"), true
+}
@@ -0,0 +1,236 @@
+package logger
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestWriterSplitsLines(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "writer.log"})
+
+	w := logger.Writer(zerolog.WarnLevel)
+	fmt.Fprint(w, "first line\nsecond ")
+	fmt.Fprint(w, "line\r\n\nthird")
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	logger.Close()
+
+	lines := strings.Split(strings.TrimSpace(readLogFile(t, filepath.Join(tmpDir, "writer.log"))), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 log entries, got %d: %v", len(lines), lines)
+	}
+	for i, want := range []string{`"message":"first line"`, `"message":"second line"`, `"message":"third"`} {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("Entry %d: expected %s, got %s", i, want, lines[i])
+		}
+		if !strings.Contains(lines[i], `"level":"warn"`) {
+			t.Errorf("Entry %d: expected warn level, got %s", i, lines[i])
+		}
+	}
+}
+
+func TestWriterPartialLineBufferedUntilClose(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "partial.log"})
+	defer logger.Close()
+
+	w := logger.Writer(zerolog.InfoLevel)
+	fmt.Fprint(w, "no newline yet")
+
+	logFile := filepath.Join(tmpDir, "partial.log")
+	if strings.Contains(readLogFileIfExists(logFile), "no newline yet") {
+		t.Fatal("Partial line should be buffered until Close")
+	}
+
+	w.Close()
+	if !strings.Contains(readLogFile(t, logFile), "no newline yet") {
+		t.Error("Expected partial line to be flushed on Close")
+	}
+}
+
+func TestWriterLongPartialLine(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "long.log"})
+
+	w := logger.Writer(zerolog.InfoLevel)
+	fmt.Fprint(w, strings.Repeat("x", maxPartialLine+10))
+	if len(w.buf) != 10 {
+		t.Errorf("Expected 10 buffered bytes after flushing a full chunk, got %d", len(w.buf))
+	}
+	w.Close()
+	logger.Close()
+
+	lines := strings.Split(strings.TrimSpace(readLogFile(t, filepath.Join(tmpDir, "long.log"))), "\n")
+	if len(lines) != 2 {
+		t.Errorf("Expected the long line to be logged in 2 pieces, got %d", len(lines))
+	}
+}
+
+func TestWriterLongLineRuneBoundary(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "long.log"})
+
+	// The 3-byte rune straddles the chunk limit
+	w := logger.Writer(zerolog.InfoLevel)
+	fmt.Fprint(w, strings.Repeat("x", maxPartialLine-1)+"€"+"tail")
+	w.Close()
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "long.log"))
+	if strings.Contains(logStr, `\ufffd`) || !strings.Contains(logStr, `"message":"€tail"`) {
+		t.Errorf("Expected the rune to start the second piece intact, got %s", logStr[len(logStr)-100:])
+	}
+}
+
+func TestWriterLineAtLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "limit.log"})
+
+	// A partial line of exactly the limit is kept whole until it ends
+	w := logger.Writer(zerolog.InfoLevel)
+	fmt.Fprint(w, strings.Repeat("a", maxPartialLine))
+	w.Close()
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "limit.log"))
+	if n := strings.Count(logStr, "\n"); n != 1 || !strings.Contains(logStr, strings.Repeat("a", maxPartialLine)) {
+		t.Errorf("Expected one entry with the whole line, got %d entries", n)
+	}
+}
+
+func TestWriterParseLevels(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{Level: "debug", LogDir: tmpDir, Filename: "levels.log"})
+
+	w := logger.Writer(zerolog.InfoLevel)
+	w.ParseLevels = true
+	fmt.Fprintln(w, "[ERROR] disk full")
+	fmt.Fprintln(w, "warn: slow query")
+	fmt.Fprintln(w, "DEBUG cache miss")
+	fmt.Fprintln(w, "Error connecting is just a sentence")
+	fmt.Fprintln(w, "plain line")
+	w.Close()
+	logger.Close()
+
+	lines := strings.Split(strings.TrimSpace(readLogFile(t, filepath.Join(tmpDir, "levels.log"))), "\n")
+	expected := []struct {
+		level   string
+		message string
+	}{
+		{"error", "disk full"},
+		{"warn", "slow query"},
+		{"debug", "cache miss"},
+		{"info", "Error connecting is just a sentence"},
+		{"info", "plain line"},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d", len(expected), len(lines))
+	}
+	for i, want := range expected {
+		if !strings.Contains(lines[i], `"level":"`+want.level+`"`) {
+			t.Errorf("Entry %d: expected level %s, got %s", i, want.level, lines[i])
+		}
+		if !strings.Contains(lines[i], `"message":"`+want.message+`"`) {
+			t.Errorf("Entry %d: expected message %q, got %s", i, want.message, lines[i])
+		}
+	}
+}
+
+func TestParseLevelPrefix(t *testing.T) {
+	tests := []struct {
+		line      string
+		wantLevel zerolog.Level
+		wantRest  string
+		wantOK    bool
+	}{
+		{"[ERROR] boom", zerolog.ErrorLevel, "boom", true},
+		{"[warning]  slow", zerolog.WarnLevel, "slow", true},
+		{"INFO: started", zerolog.InfoLevel, "started", true},
+		{"fatal: stopping", zerolog.ErrorLevel, "stopping", true},
+		{"WARN retrying", zerolog.WarnLevel, "retrying", true},
+		{"Warn retrying", zerolog.NoLevel, "Warn retrying", false},
+		{"[unknown] text", zerolog.NoLevel, "[unknown] text", false},
+		{"[ERROR unterminated", zerolog.NoLevel, "[ERROR unterminated", false},
+		{"ERROR", zerolog.NoLevel, "ERROR", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			level, rest, ok := parseLevelPrefix(tt.line)
+			if ok != tt.wantOK || level != tt.wantLevel || rest != tt.wantRest {
+				t.Errorf("parseLevelPrefix(%q) = (%v, %q, %v), want (%v, %q, %v)",
+					tt.line, level, rest, ok, tt.wantLevel, tt.wantRest, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "stdlogger.log"})
+
+	std := logger.StdLogger(zerolog.ErrorLevel)
+	std.Printf("dependency failed: %d", 42)
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "stdlogger.log"))
+	if !strings.Contains(logStr, `"message":"dependency failed: 42"`) {
+		t.Errorf("Expected std logger message, got: %s", logStr)
+	}
+	if !strings.Contains(logStr, `"level":"error"`) {
+		t.Errorf("Expected error level, got: %s", logStr)
+	}
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "redirect.log"})
+
+	prevOutput, prevFlags := log.Writer(), log.Flags()
+	restore := logger.RedirectStdLog()
+	log.Print("[WARN] from global logger")
+	restore()
+	logger.Close()
+
+	if log.Writer() != prevOutput || log.Flags() != prevFlags {
+		t.Error("Expected restore to reinstate the previous global logger settings")
+	}
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "redirect.log"))
+	if !strings.Contains(logStr, `"message":"from global logger"`) {
+		t.Errorf("Expected redirected message, got: %s", logStr)
+	}
+	if !strings.Contains(logStr, `"level":"warn"`) {
+		t.Errorf("Expected parsed warn level, got: %s", logStr)
+	}
+}
+
+func TestWriterRespectsLoggerLevel(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{Level: "warn", LogDir: tmpDir, Filename: "filtered.log"})
+
+	w := logger.Writer(zerolog.InfoLevel)
+	fmt.Fprintln(w, "filtered out")
+	w.Close()
+	logger.Close()
+
+	if strings.Contains(readLogFileIfExists(filepath.Join(tmpDir, "filtered.log")), "filtered out") {
+		t.Error("Expected info line to be filtered at warn level")
+	}
+}
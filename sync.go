@@ -0,0 +1,181 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// SyncConfig sets when the log file is flushed to stable storage with fsync.
+// Without a policy, durability is left to the OS page cache, and a kernel
+// panic or power loss can cost recent entries. Policies combine: an entry is
+// synced as soon as any of them calls for it.
+type SyncConfig struct {
+	Every    int           // fsync after this many entries (default: 0, off)
+	Interval time.Duration // fsync pending entries this often (default: 0, off)
+	Level    string        // fsync right after entries at or above this level, e.g. "error" (default: "", off)
+}
+
+// enabled reports whether any sync policy is configured
+func (c SyncConfig) enabled() bool {
+	return c.Every > 0 || c.Interval > 0 || c.Level != ""
+}
+
+// syncer is implemented by file writers that can flush to stable storage
+type syncer interface {
+	Sync() error
+}
+
+// pathSyncer syncs a file by path, for writers such as lumberjack that do not
+// expose their file. fsync flushes the file's data whichever descriptor it
+// is called on.
+type pathSyncer string
+
+// Sync implements syncer
+func (p pathSyncer) Sync() error {
+	f, err := os.OpenFile(string(p), os.O_WRONLY|os.O_APPEND, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
+// syncWriter applies a SyncConfig to the file output
+type syncWriter struct {
+	next  io.Writer
+	file  syncer
+	every int
+	level zerolog.Level // Entries at or above it are synced, zerolog.Disabled for none
+	stats *loggerStats
+
+	mu      sync.Mutex
+	pending int // Entries written since the last successful sync
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newSyncWriter wraps next, syncing file according to cfg. It returns an
+// error for an unknown cfg.Level, in which case the level policy is off.
+func newSyncWriter(next io.Writer, file syncer, cfg SyncConfig, stats *loggerStats) (*syncWriter, error) {
+	w := &syncWriter{next: next, file: file, every: cfg.Every, level: zerolog.Disabled, stats: stats}
+
+	var err error
+	if cfg.Level != "" {
+		var level zerolog.Level
+		if level, err = zerolog.ParseLevel(cfg.Level); err == nil {
+			w.level = level
+		}
+	}
+
+	if cfg.Interval > 0 {
+		w.stop, w.done = make(chan struct{}), make(chan struct{})
+		go w.loop(cfg.Interval)
+	}
+	return w, err
+}
+
+// Write implements io.Writer. A failed sync is returned, so zerolog reports
+// it, and retried with the next entry.
+func (w *syncWriter) Write(p []byte) (int, error) {
+	n, err := w.next.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending++
+	if (w.every > 0 && w.pending >= w.every) || w.levelDue(p) {
+		return n, w.syncLocked()
+	}
+	return n, nil
+}
+
+// Sync flushes every entry written so far
+func (w *syncWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.syncLocked()
+}
+
+// Close stops the interval loop and syncs pending entries
+func (w *syncWriter) Close() error {
+	if w.stop != nil {
+		close(w.stop)
+		<-w.done
+		w.stop = nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.pending == 0 {
+		return nil
+	}
+	return w.syncLocked()
+}
+
+// syncLocked must be called with w.mu held
+func (w *syncWriter) syncLocked() error {
+	if err := w.file.Sync(); err != nil {
+		w.stats.syncErrors.Add(1)
+		return err
+	}
+	w.pending = 0
+	return nil
+}
+
+// loop syncs pending entries every interval until Close
+func (w *syncWriter) loop(interval time.Duration) {
+	defer close(w.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.pending > 0 {
+				_ = w.syncLocked()
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// levelDue reports whether the entry's level calls for an immediate sync.
+// zerolog writes the level as the first field.
+func (w *syncWriter) levelDue(p []byte) bool {
+	if w.level == zerolog.Disabled {
+		return false
+	}
+	prefix := `{"` + zerolog.LevelFieldName + `":"`
+	rest, ok := bytes.CutPrefix(p, []byte(prefix))
+	if !ok {
+		return false
+	}
+	end := bytes.IndexByte(rest, '"')
+	if end < 0 {
+		return false
+	}
+	level, err := zerolog.ParseLevel(string(rest[:end]))
+	return err == nil && level >= w.level && level != zerolog.NoLevel
+}
+
+// Sync flushes the entries written to the log file so far to stable storage
+func (l *Logger) Sync() error {
+	if l.syncer == nil {
+		return nil
+	}
+	return l.syncer.Sync()
+}
@@ -0,0 +1,247 @@
+package logger
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// faultyFile is a fault-injecting file that records writes and syncs
+type faultyFile struct {
+	mu        sync.Mutex
+	writes    int
+	synced    int // Writes covered by the last successful sync
+	syncs     int
+	failWrite bool
+	failSync  bool
+}
+
+func (f *faultyFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failWrite {
+		return 0, errors.New("injected write failure")
+	}
+	f.writes++
+	return len(p), nil
+}
+
+func (f *faultyFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failSync {
+		return errors.New("injected sync failure")
+	}
+	f.syncs++
+	f.synced = f.writes
+	return nil
+}
+
+// state returns the number of writes, syncs and writes not yet synced
+func (f *faultyFile) state() (writes, syncs, unsynced int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.writes, f.syncs, f.writes - f.synced
+}
+
+// newTestSyncWriter returns a syncWriter over a new faultyFile
+func newTestSyncWriter(t *testing.T, cfg SyncConfig) (*syncWriter, *faultyFile, *loggerStats) {
+	t.Helper()
+	f := &faultyFile{}
+	stats := &loggerStats{}
+	w, err := newSyncWriter(f, f, cfg, stats)
+	if err != nil {
+		t.Fatalf("newSyncWriter returned error: %v", err)
+	}
+	return w, f, stats
+}
+
+func TestSyncEveryN(t *testing.T) {
+	w, f, _ := newTestSyncWriter(t, SyncConfig{Every: 3})
+	for i := 0; i < 7; i++ {
+		w.Write([]byte(`{"level":"info"}` + "\n"))
+	}
+	if _, syncs, unsynced := f.state(); syncs != 2 || unsynced != 1 {
+		t.Errorf("Expected 2 syncs and 1 pending entry, got %d syncs, %d pending", syncs, unsynced)
+	}
+
+	// Close flushes the remainder
+	w.Close()
+	if _, syncs, unsynced := f.state(); syncs != 3 || unsynced != 0 {
+		t.Errorf("Expected Close to sync the pending entry, got %d syncs, %d pending", syncs, unsynced)
+	}
+}
+
+func TestSyncPerLevel(t *testing.T) {
+	w, f, _ := newTestSyncWriter(t, SyncConfig{Level: "error"})
+	defer w.Close()
+
+	tests := []struct {
+		line     string
+		wantSync bool
+	}{
+		{`{"level":"debug","message":"x"}`, false},
+		{`{"level":"info","message":"mentions \"level\":\"error\""}`, false},
+		{`{"level":"warn"}`, false},
+		{`{"level":"error"}`, true},
+		{`{"level":"fatal"}`, true},
+		{`{"level":"panic"}`, true},
+		{`{"message":"no level"}`, false},
+		{`not json`, false},
+	}
+	for _, tt := range tests {
+		_, before, _ := f.state()
+		w.Write([]byte(tt.line + "\n"))
+		_, after, _ := f.state()
+		if (after > before) != tt.wantSync {
+			t.Errorf("Entry %s: expected sync %v", tt.line, tt.wantSync)
+		}
+	}
+}
+
+func TestSyncInterval(t *testing.T) {
+	w, f, _ := newTestSyncWriter(t, SyncConfig{Interval: 5 * time.Millisecond})
+	defer w.Close()
+
+	w.Write([]byte(`{"level":"info"}` + "\n"))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, _, unsynced := f.state(); unsynced == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the interval sync")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Nothing pending, so idle ticks do not sync
+	_, syncs, _ := f.state()
+	time.Sleep(30 * time.Millisecond)
+	if _, later, _ := f.state(); later != syncs {
+		t.Errorf("Expected no syncs while idle, got %d more", later-syncs)
+	}
+}
+
+func TestSyncFailureIsReportedAndRetried(t *testing.T) {
+	w, f, stats := newTestSyncWriter(t, SyncConfig{Every: 1})
+	defer w.Close()
+
+	f.failSync = true
+	if _, err := w.Write([]byte("{}\n")); err == nil {
+		t.Error("Expected the sync failure to be returned")
+	}
+	if stats.syncErrors.Load() != 1 {
+		t.Errorf("Expected 1 sync error counted, got %d", stats.syncErrors.Load())
+	}
+
+	f.failSync = false
+	if _, err := w.Write([]byte("{}\n")); err != nil {
+		t.Errorf("Write returned error: %v", err)
+	}
+	if _, _, unsynced := f.state(); unsynced != 0 {
+		t.Errorf("Expected the retry to cover both entries, %d pending", unsynced)
+	}
+
+	// A failed write is not synced
+	f.failWrite = true
+	_, before, _ := f.state()
+	if _, err := w.Write([]byte("{}\n")); err == nil {
+		t.Error("Expected the write failure to be returned")
+	}
+	if _, after, _ := f.state(); after != before {
+		t.Error("Expected no sync after a failed write")
+	}
+}
+
+func TestSyncInvalidLevel(t *testing.T) {
+	f := &faultyFile{}
+	w, err := newSyncWriter(f, f, SyncConfig{Level: "loud", Every: 2}, &loggerStats{})
+	if err == nil {
+		t.Error("Expected error for an unknown level")
+	}
+	defer w.Close()
+	w.Write([]byte(`{"level":"error"}` + "\n"))
+	w.Write([]byte(`{"level":"error"}` + "\n"))
+	if _, syncs, _ := f.state(); syncs != 1 {
+		t.Errorf("Expected the other policies to keep working, got %d syncs", syncs)
+	}
+}
+
+func TestSyncOnRotationFollowsPolicy(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	plain := New(Config{LogDir: tmpDir, Filename: "plain.log", FileMode: 0600})
+	defer plain.Close()
+	if plain.fileWriter.(*rotatingWriter).opts.syncClose {
+		t.Error("Expected no fsync on rotation without a sync policy")
+	}
+	synced := New(Config{LogDir: tmpDir, Filename: "synced.log", Sync: SyncConfig{Every: 100}})
+	defer synced.Close()
+	if !synced.fileWriter.(*rotatingWriter).opts.syncClose {
+		t.Error("Expected fsync on rotation with a sync policy")
+	}
+}
+
+func TestLoggerSync(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Without a policy the lumberjack file is synced by path
+	plain := New(Config{LogDir: tmpDir, Filename: "plain.log"})
+	plain.Info().Msg("entry")
+	if err := plain.Sync(); err != nil {
+		t.Errorf("Sync returned error: %v", err)
+	}
+	plain.Close()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "synced.log", Sync: SyncConfig{Level: "error", Interval: time.Hour}})
+	if _, ok := logger.fileWriter.(*rotatingWriter); !ok {
+		t.Errorf("Expected a sync policy to use the built-in writer, got %T", logger.fileWriter)
+	}
+	logger.WithField("k", "v").Error().Msg("synced")
+	if err := logger.WithField("k", "v").Sync(); err != nil {
+		t.Errorf("Sync returned error: %v", err)
+	}
+	logger.Close()
+	if logger.Stats().SyncErrors != 0 {
+		t.Errorf("Expected no sync errors, got %d", logger.Stats().SyncErrors)
+	}
+
+	if err := createStderrLogger("test").Sync(); err != nil {
+		t.Errorf("Expected Sync on a stderr logger to be a no-op, got %v", err)
+	}
+	if !strings.Contains(readLogFile(t, filepath.Join(tmpDir, "synced.log")), `"message":"synced"`) {
+		t.Error("Expected the entry in the log file")
+	}
+}
+
+func BenchmarkSyncPolicy(b *testing.B) {
+	policies := []struct {
+		name string
+		cfg  SyncConfig
+	}{
+		{"never", SyncConfig{}},
+		{"every-1", SyncConfig{Every: 1}},
+		{"every-100", SyncConfig{Every: 100}},
+		{"interval-100ms", SyncConfig{Interval: 100 * time.Millisecond}},
+		{"level-error-info-entries", SyncConfig{Level: "error"}},
+		{"level-error-error-entries", SyncConfig{Level: "error"}},
+	}
+	for _, p := range policies {
+		b.Run(p.name, func(b *testing.B) {
+			logger := New(Config{LogDir: b.TempDir(), FileMode: 0600, DisableCaller: true, Sync: p.cfg})
+			defer logger.Close()
+			event := logger.Info
+			if p.name == "level-error-error-entries" {
+				event = logger.Error
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				event().Int("i", i).Msg("benchmark entry")
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package logger
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LogTagName is the struct tag that controls how fields are logged:
+//
+//	Password string `log:"-"`            // omitted
+//	Token    string `log:"redact"`       // replaced with RedactedPlaceholder
+//	Email    string `log:"hash"`         // replaced with a short SHA-256 digest
+//	Bio      string `log:"truncate=32"`  // cut to 32 characters
+const LogTagName = "log"
+
+// tagAction is the parsed form of a log struct tag
+type tagAction int
+
+const (
+	tagNone tagAction = iota
+	tagOmit
+	tagRedact
+	tagHash
+	tagTruncate
+)
+
+// fieldInfo is the cached metadata of one exported struct field
+type fieldInfo struct {
+	index     []int
+	name      string
+	omitEmpty bool
+	action    tagAction
+	keep      int
+}
+
+// typeInfo is the cached metadata of a type
+type typeInfo struct {
+	hasTags bool        // The type or a type it contains uses log tags
+	fields  []fieldInfo // Encoded fields, for struct types with tags
+}
+
+var (
+	typeInfoCache sync.Map // reflect.Type -> *typeInfo
+
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// LogValue applies log struct tags to v, recursing into nested structs,
+// pointers, slices, arrays and maps. Values whose types carry no log tags are
+// returned unchanged. WithField and WithFields apply it automatically; use it
+// directly with zerolog's Event.Interface.
+func LogValue(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	if !getTypeInfo(rv.Type()).hasTags {
+		return v
+	}
+	return taggedValue(rv, make(map[visit]struct{}))
+}
+
+// visit identifies a pointer, map or slice being encoded, as in encoding/json
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// taggedValue converts rv into a value whose JSON encoding honors log tags.
+// seen holds the references on the path from the root; a cycle is encoded as
+// an error string, like zerolog does when encoding/json rejects one.
+func taggedValue(rv reflect.Value, seen map[visit]struct{}) interface{} {
+	if !rv.IsValid() {
+		return nil
+	}
+	info := getTypeInfo(rv.Type())
+	if !info.hasTags {
+		return rv.Interface()
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			return nil
+		}
+		v := visit{ptr: rv.Pointer(), typ: rv.Type()}
+		if rv.Kind() == reflect.Slice {
+			v.len = rv.Len()
+		}
+		if _, ok := seen[v]; ok {
+			return fmt.Sprintf("marshaling error: json: unsupported value: encountered a cycle via %s", rv.Type())
+		}
+		seen[v] = struct{}{}
+		defer delete(seen, v)
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return taggedValue(rv.Elem(), seen)
+	case reflect.Slice, reflect.Array:
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = taggedValue(rv.Index(i), seen)
+		}
+		return out
+	case reflect.Map:
+		out := make(jsonObject, 0, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			out = append(out, jsonField{Key: mapKeyString(iter.Key()), Value: taggedValue(iter.Value(), seen)})
+		}
+		// Match encoding/json, which sorts map keys
+		slices.SortFunc(out, func(a, b jsonField) int { return strings.Compare(a.Key, b.Key) })
+		return out
+	case reflect.Struct:
+		return taggedStruct(rv, info, seen)
+	default:
+		return rv.Interface()
+	}
+}
+
+// taggedStruct encodes the fields of a struct in declaration order
+func taggedStruct(rv reflect.Value, info *typeInfo, seen map[visit]struct{}) jsonObject {
+	out := make(jsonObject, 0, len(info.fields))
+	for _, f := range info.fields {
+		fv, ok := fieldByIndex(rv, f.index)
+		if !ok || (f.omitEmpty && fv.IsZero()) {
+			continue
+		}
+
+		var value interface{}
+		switch f.action {
+		case tagRedact:
+			value = RedactedPlaceholder
+		case tagHash:
+			value = applyRedactAction(RedactHash, 0, reflectString(fv))
+		case tagTruncate:
+			value = applyRedactAction(RedactTruncate, f.keep, reflectString(fv))
+		default:
+			value = taggedValue(fv, seen)
+		}
+		out = append(out, jsonField{Key: f.name, Value: value})
+	}
+	return out
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but reports false instead
+// of panicking when an embedded pointer is nil
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				return reflect.Value{}, false
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, true
+}
+
+// reflectString returns the text used for hashing and truncating a value
+func reflectString(rv reflect.Value) string {
+	if rv.Kind() == reflect.String {
+		return rv.String()
+	}
+	b, err := json.Marshal(rv.Interface())
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// mapKeyString converts a map key to its JSON object key
+func mapKeyString(k reflect.Value) string {
+	if k.Kind() == reflect.String {
+		return k.String()
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if b, err := tm.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10)
+	}
+	return ""
+}
+
+// getTypeInfo returns the cached metadata for t, computing it on first use
+func getTypeInfo(t reflect.Type) *typeInfo {
+	if cached, ok := typeInfoCache.Load(t); ok {
+		return cached.(*typeInfo)
+	}
+	info := buildTypeInfo(t, map[reflect.Type]bool{})
+	actual, _ := typeInfoCache.LoadOrStore(t, info)
+	return actual.(*typeInfo)
+}
+
+// buildTypeInfo inspects t. Types that marshal themselves are left alone, and
+// recursive references are cut off while a type is being inspected.
+func buildTypeInfo(t reflect.Type, visiting map[reflect.Type]bool) *typeInfo {
+	if cached, ok := typeInfoCache.Load(t); ok {
+		return cached.(*typeInfo)
+	}
+	if visiting[t] || t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return &typeInfo{}
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &typeInfo{} // []byte encodes as base64
+		}
+		return &typeInfo{hasTags: buildTypeInfo(t.Elem(), visiting).hasTags}
+	case reflect.Map:
+		return &typeInfo{hasTags: buildTypeInfo(t.Elem(), visiting).hasTags}
+	case reflect.Interface:
+		// Dynamic values are inspected when encoded
+		return &typeInfo{hasTags: true}
+	case reflect.Struct:
+		info := &typeInfo{}
+		info.fields, info.hasTags = structFields(t, nil, visiting)
+		return info
+	default:
+		return &typeInfo{}
+	}
+}
+
+// structFields lists the encoded fields of t following encoding/json naming
+// rules, with embedded structs without a JSON name flattened into the parent
+func structFields(t reflect.Type, index []int, visiting map[reflect.Type]bool) ([]fieldInfo, bool) {
+	var fields []fieldInfo
+	hasTags := false
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		jsonTag := sf.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(jsonTag, ",")
+
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && !ft.Implements(jsonMarshalerType) {
+				embedded, tagged := structFields(ft, fieldIndex, visiting)
+				fields = append(fields, embedded...)
+				hasTags = hasTags || tagged
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		f := fieldInfo{index: fieldIndex, name: name, omitEmpty: strings.Contains(opts, "omitempty")}
+		f.action, f.keep = parseLogTag(sf.Tag.Get(LogTagName))
+		switch {
+		case f.action == tagOmit:
+			hasTags = true
+			continue
+		case f.action != tagNone:
+			hasTags = true
+		case buildTypeInfo(sf.Type, visiting).hasTags:
+			hasTags = true
+		}
+		fields = append(fields, f)
+	}
+	return fields, hasTags
+}
+
+// parseLogTag parses a log struct tag value
+func parseLogTag(tag string) (tagAction, int) {
+	switch {
+	case tag == "":
+		return tagNone, 0
+	case tag == "-":
+		return tagOmit, 0
+	case tag == "redact":
+		return tagRedact, 0
+	case tag == "hash":
+		return tagHash, 0
+	case strings.HasPrefix(tag, "truncate="):
+		keep, err := strconv.Atoi(strings.TrimPrefix(tag, "truncate="))
+		if err != nil || keep <= 0 {
+			return tagRedact, 0 // Fail closed on a malformed limit
+		}
+		return tagTruncate, keep
+	default:
+		return tagRedact, 0 // Fail closed on unknown directives
+	}
+}
@@ -0,0 +1,247 @@
+package logger
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type taggedAddress struct {
+	Street string `json:"street" log:"redact"`
+	City   string `json:"city"`
+}
+
+type taggedUser struct {
+	ID       int             `json:"id"`
+	Name     string          `json:"name"`
+	Password string          `json:"password" log:"-"`
+	Token    string          `json:"token" log:"redact"`
+	Email    string          `json:"email" log:"hash"`
+	Bio      string          `json:"bio" log:"truncate=5"`
+	Nickname string          `json:"nickname,omitempty"`
+	Address  taggedAddress   `json:"address"`
+	Previous []taggedAddress `json:"previous"`
+	Manager  *taggedUser     `json:"manager,omitempty"`
+	Created  time.Time       `json:"created"`
+	internal string
+}
+
+type untaggedUser struct {
+	Name string `json:"name"`
+}
+
+type taggedEmbedded struct {
+	taggedAddress
+	Zip string `json:"zip" log:"truncate=2"`
+}
+
+// encodeLogValue returns the JSON encoding of LogValue(v)
+func encodeLogValue(t *testing.T, v interface{}) string {
+	t.Helper()
+	b, err := json.Marshal(LogValue(v))
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %v", err)
+	}
+	return string(b)
+}
+
+func TestLogValueTags(t *testing.T) {
+	u := taggedUser{
+		ID:       7,
+		Name:     "alice",
+		Password: "hunter2",
+		Token:    "tok-123",
+		Email:    "alice@example.com",
+		Bio:      "loves long walks",
+		Address:  taggedAddress{Street: "1 Main St", City: "Springfield"},
+		Previous: []taggedAddress{{Street: "2 Old Rd", City: "Shelbyville"}},
+		Manager:  &taggedUser{Name: "bob", Password: "bobpass"},
+		internal: "hidden",
+	}
+
+	got := encodeLogValue(t, u)
+
+	for _, leaked := range []string{"hunter2", "tok-123", "alice@example.com", "1 Main St", "2 Old Rd", "bobpass", "hidden", "long walks"} {
+		if strings.Contains(got, leaked) {
+			t.Errorf("Expected %q to be hidden, got %s", leaked, got)
+		}
+	}
+	for _, want := range []string{
+		`"id":7`,
+		`"name":"alice"`,
+		`"token":"[REDACTED]"`,
+		`"email":"sha256:`,
+		`"bio":"loves…"`,
+		`"address":{"street":"[REDACTED]","city":"Springfield"}`,
+		`"previous":[{"street":"[REDACTED]","city":"Shelbyville"}]`,
+		`"manager":{"id":0,"name":"bob"`,
+		`"created":"0001-01-01T00:00:00Z"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %s in %s", want, got)
+		}
+	}
+	if strings.Contains(got, "password") || strings.Contains(got, "nickname") {
+		t.Errorf("Expected omitted fields to be absent: %s", got)
+	}
+	if !strings.HasPrefix(got, `{"id":7,"name":"alice","token"`) {
+		t.Errorf("Expected declaration order to be preserved: %s", got)
+	}
+}
+
+func TestLogValueUntaggedUnchanged(t *testing.T) {
+	values := []interface{}{
+		"plain",
+		42,
+		untaggedUser{Name: "carol"},
+		[]untaggedUser{{Name: "dave"}},
+		map[string]int{"a": 1},
+		[]byte("raw"),
+		time.Unix(0, 0),
+	}
+	for _, v := range values {
+		if got := LogValue(v); !reflect.DeepEqual(got, v) {
+			t.Errorf("Expected untagged value %#v to be returned unchanged, got %#v", v, got)
+		}
+	}
+	if LogValue(nil) != nil {
+		t.Error("Expected nil to stay nil")
+	}
+}
+
+func TestLogValueCollections(t *testing.T) {
+	users := []taggedUser{{Name: "a", Token: "t1"}, {Name: "b", Token: "t2"}}
+	got := encodeLogValue(t, users)
+	if strings.Contains(got, "t1") || strings.Contains(got, "t2") {
+		t.Errorf("Expected tokens in slice elements to be redacted: %s", got)
+	}
+
+	byRole := map[string]*taggedUser{"z-admin": {Token: "admin-token"}, "a-guest": nil}
+	got = encodeLogValue(t, byRole)
+	if strings.Contains(got, "admin-token") {
+		t.Errorf("Expected tokens in map values to be redacted: %s", got)
+	}
+	if !strings.HasPrefix(got, `{"a-guest":null,"z-admin":`) {
+		t.Errorf("Expected sorted map keys: %s", got)
+	}
+
+	var nilUser *taggedUser
+	if LogValue(nilUser) != nil {
+		t.Error("Expected nil pointer to encode as null")
+	}
+
+	dynamic := map[string]interface{}{"user": taggedUser{Token: "dyn-token"}}
+	if got := encodeLogValue(t, dynamic); strings.Contains(got, "dyn-token") {
+		t.Errorf("Expected tagged values behind interfaces to be redacted: %s", got)
+	}
+}
+
+type taggedNode struct {
+	Token string      `log:"redact"`
+	Next  *taggedNode `json:"next"`
+}
+
+func TestLogValueCycle(t *testing.T) {
+	n := &taggedNode{Token: "secret"}
+	n.Next = n
+	got := encodeLogValue(t, n)
+	if strings.Contains(got, "secret") || !strings.Contains(got, "encountered a cycle via *logger.taggedNode") {
+		t.Errorf("Expected the cycle to be reported as an error: %s", got)
+	}
+
+	// A value referenced twice without a cycle is encoded both times
+	shared := &taggedNode{Token: "t"}
+	got = encodeLogValue(t, []*taggedNode{shared, shared})
+	if strings.Contains(got, "cycle") {
+		t.Errorf("Expected repeated references not to be a cycle: %s", got)
+	}
+
+	tmpDir := t.TempDir()
+	logger := New(Config{LogDir: tmpDir, Filename: "cycle.log"})
+	logger.WithField("n", n).Info().Msg("cyclic")
+	logger.Close()
+	if logStr := readLogFile(t, filepath.Join(tmpDir, "cycle.log")); !strings.Contains(logStr, `"message":"cyclic"`) {
+		t.Errorf("Expected the entry to be logged: %s", logStr)
+	}
+}
+
+func TestLogValueEmbedded(t *testing.T) {
+	got := encodeLogValue(t, taggedEmbedded{taggedAddress: taggedAddress{Street: "secret st", City: "c"}, Zip: "12345"})
+	if got != `{"street":"[REDACTED]","city":"c","zip":"12…"}` {
+		t.Errorf("Unexpected embedded encoding: %s", got)
+	}
+}
+
+func TestParseLogTag(t *testing.T) {
+	tests := []struct {
+		tag        string
+		wantAction tagAction
+		wantKeep   int
+	}{
+		{"", tagNone, 0},
+		{"-", tagOmit, 0},
+		{"redact", tagRedact, 0},
+		{"hash", tagHash, 0},
+		{"truncate=32", tagTruncate, 32},
+		{"truncate=abc", tagRedact, 0},
+		{"truncate=0", tagRedact, 0},
+		{"unknown", tagRedact, 0},
+	}
+	for _, tt := range tests {
+		action, keep := parseLogTag(tt.tag)
+		if action != tt.wantAction || keep != tt.wantKeep {
+			t.Errorf("parseLogTag(%q) = (%v, %d), want (%v, %d)", tt.tag, action, keep, tt.wantAction, tt.wantKeep)
+		}
+	}
+}
+
+func TestTypeInfoCached(t *testing.T) {
+	typ := reflect.TypeOf(taggedUser{})
+	first := getTypeInfo(typ)
+	second := getTypeInfo(typ)
+	if first != second {
+		t.Error("Expected type metadata to be cached")
+	}
+	if !first.hasTags {
+		t.Error("Expected taggedUser to be detected as tagged")
+	}
+}
+
+func TestWithFieldHonorsLogTags(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "tags.log"})
+	logger.
+		WithField("user", taggedUser{Name: "erin", Password: "pw", Token: "tk"}).
+		WithFields(map[string]interface{}{"team": []taggedUser{{Token: "team-token"}}}).
+		Info().
+		Msg("tagged fields")
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "tags.log"))
+	for _, leaked := range []string{`"pw"`, `"tk"`, "team-token"} {
+		if strings.Contains(logStr, leaked) {
+			t.Errorf("Expected %s to be hidden, got %s", leaked, logStr)
+		}
+	}
+	if !strings.Contains(logStr, `"user":{"id":0,"name":"erin"`) {
+		t.Errorf("Expected ordered user object, got %s", logStr)
+	}
+}
+
+func BenchmarkLogValueTagged(b *testing.B) {
+	u := taggedUser{Name: "alice", Token: "tok", Email: "a@b.io", Previous: []taggedAddress{{City: "x"}}}
+	for i := 0; i < b.N; i++ {
+		_ = LogValue(u)
+	}
+}
+
+func BenchmarkLogValueUntagged(b *testing.B) {
+	u := untaggedUser{Name: "alice"}
+	for i := 0; i < b.N; i++ {
+		_ = LogValue(u)
+	}
+}
@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Field names used to correlate log entries with traces
+const (
+	TraceIDFieldName    = "trace_id"
+	SpanIDFieldName     = "span_id"
+	TraceFlagsFieldName = "trace_flags"
+)
+
+// SpanEventName is the name of span events recorded when Config.TraceSpanEvents is enabled
+const SpanEventName = "log"
+
+// ErrInvalidTraceparent is returned when a W3C traceparent header cannot be parsed
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// Ctx returns a child logger bound to ctx.
+//
+// When ctx carries a valid span context (an active OpenTelemetry span or a
+// traceparent stored with ContextWithTraceparent), the trace_id, span_id and
+// trace_flags fields are added to every entry. The context is also attached
+// to the logger so hooks such as the span event hook can reach the span.
+// A level set with ContextWithLevel or ContextFromRequest overrides the
+// instance level.
+func (l *Logger) Ctx(ctx context.Context) *Logger {
+	if ctx == nil {
+		return l
+	}
+
+	zctx := l.Logger.With().Ctx(ctx)
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		zctx = zctx.
+			Str(TraceIDFieldName, sc.TraceID().String()).
+			Str(SpanIDFieldName, sc.SpanID().String()).
+			Str(TraceFlagsFieldName, sc.TraceFlags().String())
+	}
+	zl := zctx.Logger()
+	if level, ok := levelFromContext(ctx); ok {
+		zl = zl.Level(level)
+	}
+	child := l.derive(zl)
+	child.ctx = ctx
+	return child
+}
+
+// ContextWithTraceparent parses a W3C traceparent header and stores the
+// resulting remote span context in ctx. This allows trace correlation for
+// services that only propagate traceparent and do not run an OpenTelemetry SDK.
+func ContextWithTraceparent(ctx context.Context, traceparent string) (context.Context, error) {
+	sc, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return ctx, err
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, sc), nil
+}
+
+// ParseTraceparent parses a W3C traceparent header value
+// (version-traceid-parentid-flags) into a remote span context.
+func ParseTraceparent(traceparent string) (trace.SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 {
+		return trace.SpanContext{}, ErrInvalidTraceparent
+	}
+
+	version := parts[0]
+	if len(version) != 2 || !isLowerHex(version) || version == "ff" {
+		return trace.SpanContext{}, ErrInvalidTraceparent
+	}
+	// Version 00 defines exactly four fields; later versions may append more
+	if version == "00" && len(parts) != 4 {
+		return trace.SpanContext{}, ErrInvalidTraceparent
+	}
+
+	if len(parts[1]) != 32 || !isLowerHex(parts[1]) {
+		return trace.SpanContext{}, ErrInvalidTraceparent
+	}
+	traceID, err := trace.TraceIDFromHex(parts[1])
+	if err != nil {
+		return trace.SpanContext{}, ErrInvalidTraceparent
+	}
+
+	if len(parts[2]) != 16 || !isLowerHex(parts[2]) {
+		return trace.SpanContext{}, ErrInvalidTraceparent
+	}
+	spanID, err := trace.SpanIDFromHex(parts[2])
+	if err != nil {
+		return trace.SpanContext{}, ErrInvalidTraceparent
+	}
+
+	if len(parts[3]) != 2 || !isLowerHex(parts[3]) {
+		return trace.SpanContext{}, ErrInvalidTraceparent
+	}
+	flags := trace.TraceFlags(hexNibble(parts[3][0])<<4 | hexNibble(parts[3][1]))
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: flags,
+		Remote:     true,
+	}), nil
+}
+
+// isLowerHex reports whether s consists only of lowercase hex digits
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// hexNibble converts a lowercase hex digit to its value
+func hexNibble(c byte) byte {
+	if c >= 'a' {
+		return c - 'a' + 10
+	}
+	return c - '0'
+}
+
+// spanEventHook records error-level entries as events on the span found in
+// the event's context
+type spanEventHook struct{}
+
+// Run implements zerolog.Hook
+func (spanEventHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if level < zerolog.ErrorLevel || level > zerolog.PanicLevel {
+		return
+	}
+	ctx := e.GetCtx()
+	if ctx == nil {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	span.AddEvent(SpanEventName, trace.WithAttributes(
+		attribute.String("log.severity", level.String()),
+		attribute.String("log.message", msg),
+	))
+}
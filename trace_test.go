@@ -0,0 +1,193 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// recordingSpan is a minimal recording span used to observe span events
+type recordingSpan struct {
+	noop.Span
+	sc     trace.SpanContext
+	events []string
+}
+
+func (s *recordingSpan) SpanContext() trace.SpanContext { return s.sc }
+func (s *recordingSpan) IsRecording() bool              { return true }
+func (s *recordingSpan) AddEvent(name string, _ ...trace.EventOption) {
+	s.events = append(s.events, name)
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		wantErr     bool
+	}{
+		{"valid sampled", testTraceparent, false},
+		{"valid not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", false},
+		{"future version with extra field", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"surrounding whitespace", "  " + testTraceparent + " ", false},
+		{"empty", "", true},
+		{"too few parts", "00-4bf92f3577b34da6a3ce929d0e0e4736-01", true},
+		{"version 00 with extra field", testTraceparent + "-extra", true},
+		{"invalid version ff", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"uppercase trace id", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", true},
+		{"short trace id", "00-4bf92f3577b34da6-00f067aa0ba902b7-01", true},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", true},
+		{"zero span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", true},
+		{"bad flags", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(tt.traceparent)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTraceparent) {
+					t.Errorf("Expected ErrInvalidTraceparent, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !sc.IsValid() || !sc.IsRemote() {
+				t.Error("Expected a valid remote span context")
+			}
+		})
+	}
+}
+
+func TestParseTraceparentFields(t *testing.T) {
+	sc, err := ParseTraceparent(testTraceparent)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := sc.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Unexpected trace id %s", got)
+	}
+	if got := sc.SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("Unexpected span id %s", got)
+	}
+	if !sc.IsSampled() {
+		t.Error("Expected sampled flag to be set")
+	}
+}
+
+func TestCtxAddsTraceFieldsFromTraceparent(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "trace.log"})
+
+	ctx, err := ContextWithTraceparent(context.Background(), testTraceparent)
+	if err != nil {
+		t.Fatalf("ContextWithTraceparent returned error: %v", err)
+	}
+
+	logger.Ctx(ctx).Info().Msg("traced message")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "trace.log"))
+	for _, want := range []string{
+		`"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`,
+		`"span_id":"00f067aa0ba902b7"`,
+		`"trace_flags":"01"`,
+	} {
+		if !strings.Contains(logStr, want) {
+			t.Errorf("Expected %s in log output, got: %s", want, logStr)
+		}
+	}
+}
+
+func TestCtxWithoutSpan(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir, Filename: "nospan.log"})
+	logger.Ctx(context.Background()).Info().Msg("untraced message")
+	logger.Close()
+
+	logStr := readLogFile(t, filepath.Join(tmpDir, "nospan.log"))
+	if strings.Contains(logStr, TraceIDFieldName) {
+		t.Errorf("Did not expect trace fields without a span, got: %s", logStr)
+	}
+	if !strings.Contains(logStr, "untraced message") {
+		t.Error("Expected message to be logged")
+	}
+}
+
+func TestContextWithTraceparentInvalid(t *testing.T) {
+	ctx := context.Background()
+	got, err := ContextWithTraceparent(ctx, "garbage")
+	if err == nil {
+		t.Fatal("Expected error for invalid traceparent")
+	}
+	if got != ctx {
+		t.Error("Expected original context to be returned on error")
+	}
+}
+
+func TestCtxPreservesFileWriter(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	logger := New(Config{LogDir: tmpDir})
+	child := logger.Ctx(context.Background())
+	if child.fileWriter != logger.fileWriter {
+		t.Error("Expected Ctx to preserve fileWriter reference")
+	}
+}
+
+func TestTraceSpanEvents(t *testing.T) {
+	sc, err := ParseTraceparent(testTraceparent)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		enabled    bool
+		wantEvents int
+	}{
+		{"enabled", true, 2},
+		{"disabled", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			span := &recordingSpan{sc: sc}
+			ctx := trace.ContextWithSpan(context.Background(), span)
+
+			logger := New(Config{
+				Level:           "debug",
+				LogDir:          tmpDir,
+				TraceSpanEvents: tt.enabled,
+			})
+			defer logger.Close()
+
+			traced := logger.Ctx(ctx)
+			traced.Info().Msg("info is not recorded")
+			traced.Warn().Msg("warn is not recorded")
+			traced.Error().Msg("error is recorded")
+			traced.WithField("k", "v").Error().Msg("child error is recorded")
+
+			if len(span.events) != tt.wantEvents {
+				t.Errorf("Expected %d span events, got %d", tt.wantEvents, len(span.events))
+			}
+			for _, name := range span.events {
+				if name != SpanEventName {
+					t.Errorf("Unexpected span event name %q", name)
+				}
+			}
+		})
+	}
+}